@@ -0,0 +1,126 @@
+package osrm
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+)
+
+const (
+	// maxExcerptSize is a maximum size of a body excerpt in error messages
+	maxExcerptSize = 512
+	// maxDrainSize is a maximum size of an unread body rest drained to reuse the connection
+	maxDrainSize = 4 << 10
+	// maxPooledBufferSize is a maximum size of a body buffer returned to the pool
+	maxPooledBufferSize = 64 << 20
+)
+
+// bodyBuffers pools buffers of bodies read as a whole
+var bodyBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// bodyReader reads a response body up to the limit and keeps its beginning for error messages
+type bodyReader struct {
+	r     io.Reader
+	limit int64 // no limit if not positive
+
+	n        int64
+	excerpt  []byte
+	err      error // a read error other than EOF
+	tooLarge bool
+}
+
+func newBodyReader(r io.Reader, limit int64) *bodyReader {
+	return &bodyReader{r: r, limit: limit}
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	if b.tooLarge {
+		return 0, ErrResponseTooLarge
+	}
+
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if free := maxExcerptSize - len(b.excerpt); free > 0 {
+		if free > n {
+			free = n
+		}
+		b.excerpt = append(b.excerpt, p[:free]...)
+	}
+	if b.limit > 0 && b.n > b.limit {
+		b.tooLarge = true
+		return n, ErrResponseTooLarge
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// readAll reads the whole body of binary responses
+func (b *bodyReader) readAll() ([]byte, error) {
+	bytes, err := ioutil.ReadAll(b)
+	if err != nil {
+		return nil, b.failure(err)
+	}
+	return bytes, nil
+}
+
+// readPooled reads the whole body into a pooled buffer, the bytes are valid until release is called
+func (b *bodyReader) readPooled() ([]byte, func(), error) {
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	release := func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bodyBuffers.Put(buf)
+		}
+	}
+
+	if _, err := buf.ReadFrom(b); err != nil {
+		release()
+		return nil, nil, b.failure(err)
+	}
+	return buf.Bytes(), release, nil
+}
+
+// failure returns a read error of the body if any or the given error otherwise
+func (b *bodyReader) failure(err error) error {
+	if b.tooLarge {
+		return ErrResponseTooLarge
+	}
+	if b.err != nil {
+		return &readBodyError{b.err}
+	}
+	return err
+}
+
+// quoted returns the quoted body excerpt marked if it is truncated
+func (b *bodyReader) quoted() string {
+	return quoteExcerpt(b.excerpt, b.n > int64(len(b.excerpt)))
+}
+
+// readExcerpt reads the beginning of a body
+func readExcerpt(r io.Reader) ([]byte, bool, error) {
+	bytes, err := ioutil.ReadAll(io.LimitReader(r, maxExcerptSize+1))
+	if len(bytes) > maxExcerptSize {
+		return bytes[:maxExcerptSize], true, err
+	}
+	return bytes, false, err
+}
+
+func quoteExcerpt(excerpt []byte, truncated bool) string {
+	s := strconv.Quote(string(excerpt))
+	if truncated {
+		s += "..."
+	}
+	return s
+}
+
+// drainAndClose drains a small rest of the body, so the connection could be reused, and closes it
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainSize))
+	closeSilently(body)
+}
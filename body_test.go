@@ -0,0 +1,65 @@
+package osrm
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyReader(t *testing.T) {
+	b := newBodyReader(strings.NewReader("short body"), 0)
+	bytes, err := b.readAll()
+	require.NoError(t, err)
+	assert.Equal(t, "short body", string(bytes))
+	assert.Equal(t, `"short body"`, b.quoted())
+	assert.Equal(t, int64(10), b.n)
+}
+
+func TestBodyReaderExcerpt(t *testing.T) {
+	b := newBodyReader(iotest.OneByteReader(strings.NewReader(strings.Repeat("ab", maxExcerptSize))), 0)
+	_, err := b.readAll()
+	require.NoError(t, err)
+	assert.Equal(t, `"`+strings.Repeat("ab", maxExcerptSize/2)+`"...`, b.quoted())
+}
+
+func TestBodyReaderLimit(t *testing.T) {
+	b := newBodyReader(strings.NewReader("0123456789"), 10)
+	_, err := b.readAll()
+	require.NoError(t, err)
+
+	b = newBodyReader(strings.NewReader("0123456789"), 9)
+	_, err = b.readAll()
+	require.Equal(t, ErrResponseTooLarge, err)
+	_, err = b.Read(make([]byte, 1))
+	require.Equal(t, ErrResponseTooLarge, err)
+}
+
+func TestBodyReaderFailure(t *testing.T) {
+	b := newBodyReader(io.MultiReader(strings.NewReader("{"), iotest.TimeoutReader(strings.NewReader("}"))), 0)
+	_, err := ioutil.ReadAll(iotest.OneByteReader(b))
+	require.Error(t, err)
+
+	err = b.failure(errors.New("unexpected EOF"))
+	require.IsType(t, &readBodyError{}, err)
+	assert.EqualError(t, err, "failed to read body: timeout")
+
+	assert.EqualError(t, newBodyReader(strings.NewReader(""), 0).failure(errors.New("decode")), "decode")
+}
+
+func TestReadExcerpt(t *testing.T) {
+	excerpt, truncated, err := readExcerpt(strings.NewReader(strings.Repeat("a", maxExcerptSize)))
+	require.NoError(t, err)
+	assert.Len(t, excerpt, maxExcerptSize)
+	assert.False(t, truncated)
+
+	excerpt, truncated, err = readExcerpt(strings.NewReader(strings.Repeat("a", maxExcerptSize+1)))
+	require.NoError(t, err)
+	assert.Len(t, excerpt, maxExcerptSize)
+	assert.True(t, truncated)
+}
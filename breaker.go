@@ -0,0 +1,241 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRate      = 0.5
+	defaultMinRequests      = 10
+	defaultBreakerWindow    = 10 * time.Second
+	defaultBreakerCooldown  = 5 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned without a request when the circuit breaker of the server is open
+var ErrCircuitOpen = errors.New("osrm5: circuit breaker is open")
+
+// CircuitState represents a state of a circuit breaker
+type CircuitState int
+
+// Supported circuit breaker states
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a few probe requests through to decide whether the server recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig represents configuration options of circuit breakers.
+// Every server has its own circuit breaker.
+type CircuitBreakerConfig struct {
+	// FailureRate is a rate of failed requests within the window opening the circuit, 0.5 is used if not set
+	FailureRate float64
+	// MinRequests is a minimum number of requests within the window to open the circuit, 10 is used if not set
+	MinRequests int
+	// Window is a period the failure rate is computed for, 10s is used if not set
+	Window time.Duration
+	// Cooldown is a time the circuit stays open before probe requests, 5s is used if not set
+	Cooldown time.Duration
+	// HalfOpenRequests is a number of successful probe requests closing the circuit, 1 is used if not set
+	HalfOpenRequests int
+	// OnStateChange is called when the circuit of the server changes its state
+	OnStateChange func(serverURL string, from, to CircuitState)
+}
+
+// breakers keeps circuit breakers of servers
+type breakers struct {
+	cfg CircuitBreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// breaker is a circuit breaker of a single server
+type breaker struct {
+	cfg       *CircuitBreakerConfig
+	serverURL string
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+	// generation is incremented on every state change, so results of requests allowed
+	// in a previous state are not counted in the current one
+	generation uint64
+}
+
+func newBreakers(cfg CircuitBreakerConfig) *breakers {
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = defaultFailureRate
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaultMinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultBreakerWindow
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultBreakerCooldown
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = defaultHalfOpenRequests
+	}
+	return &breakers{cfg: cfg, breakers: map[string]*breaker{}}
+}
+
+// get returns a circuit breaker of the server, nil breakers return nil breaker letting all requests through
+func (bs *breakers) get(serverURL string) *breaker {
+	if bs == nil {
+		return nil
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, ok := bs.breakers[serverURL]
+	if !ok {
+		b = &breaker{cfg: &bs.cfg, serverURL: serverURL, windowStart: time.Now()}
+		bs.breakers[serverURL] = b
+	}
+	return b
+}
+
+// usable reports whether the circuit of the pool server could let a request through
+func (bs *breakers) usable(b *Backend) bool {
+	return bs.get(b.url).usable(time.Now())
+}
+
+func (b *breaker) usable(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		return now.Sub(b.openedAt) >= b.cfg.Cooldown
+	case CircuitHalfOpen:
+		return b.probes < b.cfg.HalfOpenRequests
+	}
+	return true
+}
+
+// allow reports whether a request could be made, a request allowed must be recorded
+// with the returned generation of the breaker
+func (b *breaker) allow() (uint64, bool) {
+	if b == nil {
+		return 0, true
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	from := b.state
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.cfg.Cooldown {
+		b.state, b.probes, b.successes = CircuitHalfOpen, 0, 0
+	}
+
+	allowed := true
+	switch b.state {
+	case CircuitOpen:
+		allowed = false
+	case CircuitHalfOpen:
+		allowed = b.probes < b.cfg.HalfOpenRequests
+		if allowed {
+			b.probes++
+		}
+	}
+	to := b.state
+	if from != to {
+		b.generation++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return generation, allowed
+}
+
+// record records a result of a request allowed in the given generation, errors caused by the caller
+// are not counted. Results of requests allowed before the last state change are stale and ignored,
+// so only probes allowed while the circuit is half-open decide whether it is closed.
+func (b *breaker) record(ctx context.Context, generation uint64, err error) {
+	if b == nil {
+		return
+	}
+
+	failed := err != nil && isBackendFailure(err)
+	ignored := (err != nil && !failed) || ctx.Err() != nil
+
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	from := b.state
+	switch b.state {
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		if ignored {
+			break
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRate*float64(b.requests) {
+			b.state, b.openedAt = CircuitOpen, now
+		}
+	case CircuitHalfOpen:
+		b.probes--
+		switch {
+		case ignored:
+		case failed:
+			b.state, b.openedAt = CircuitOpen, now
+		default:
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenRequests {
+				b.state, b.windowStart, b.requests, b.failures = CircuitClosed, now, 0, 0
+			}
+		}
+	}
+	to := b.state
+	if from != to {
+		b.generation++
+	}
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *breaker) notify(from, to CircuitState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.serverURL, from, to)
+	}
+}
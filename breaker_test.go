@@ -0,0 +1,162 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateChange struct {
+	serverURL string
+	from, to  CircuitState
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		calls  int32
+		failed int32 = 1
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failed) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+
+	var (
+		mu      sync.Mutex
+		changes []stateChange
+	)
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, CircuitBreaker: &CircuitBreakerConfig{
+		MinRequests: 2,
+		Cooldown:    20 * time.Millisecond,
+		OnStateChange: func(serverURL string, from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, stateChange{serverURL, from, to})
+		},
+	}})
+	nearest := func() error {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		return err
+	}
+
+	require.IsType(t, &StatusError{}, nearest())
+	require.IsType(t, &StatusError{}, nearest())
+	require.Equal(t, ErrCircuitOpen, nearest())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// a failed probe opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	require.IsType(t, &StatusError{}, nearest())
+	require.Equal(t, ErrCircuitOpen, nearest())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&failed, 0)
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, nearest())
+	require.NoError(t, nearest())
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	assert.Equal(t, []stateChange{
+		{ts.URL, CircuitClosed, CircuitOpen},
+		{ts.URL, CircuitOpen, CircuitHalfOpen},
+		{ts.URL, CircuitHalfOpen, CircuitOpen},
+		{ts.URL, CircuitOpen, CircuitHalfOpen},
+		{ts.URL, CircuitHalfOpen, CircuitClosed},
+	}, changes)
+}
+
+func TestCircuitBreakerIgnoresAPIErrors(t *testing.T) {
+	b := newBreakers(CircuitBreakerConfig{MinRequests: 1}).get("server")
+
+	for i := 0; i < 3; i++ {
+		generation, ok := b.allow()
+		require.True(t, ok)
+		b.record(context.Background(), generation, ResponseStatus{Code: ErrorCodeNoRoute})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	generation, ok := b.allow()
+	require.True(t, ok)
+	b.record(ctx, generation, context.Canceled)
+
+	assert.Equal(t, CircuitClosed, b.state)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	b := newBreakers(CircuitBreakerConfig{MinRequests: 2, Window: 20 * time.Millisecond}).get("server")
+
+	b.record(context.Background(), 0, &StatusError{StatusCode: http.StatusBadGateway})
+	time.Sleep(30 * time.Millisecond)
+	b.record(context.Background(), 0, &StatusError{StatusCode: http.StatusBadGateway})
+	assert.Equal(t, CircuitClosed, b.state)
+
+	b.record(context.Background(), 0, nil)
+	assert.Equal(t, CircuitOpen, b.state)
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	b := newBreakers(CircuitBreakerConfig{MinRequests: 1, Cooldown: time.Millisecond}).get("server")
+
+	slow, ok := b.allow()
+	require.True(t, ok)
+	failed, ok := b.allow()
+	require.True(t, ok)
+	b.record(context.Background(), failed, &StatusError{StatusCode: http.StatusBadGateway})
+	require.Equal(t, CircuitOpen, b.state)
+
+	time.Sleep(5 * time.Millisecond)
+	probe, ok := b.allow()
+	require.True(t, ok)
+	require.Equal(t, CircuitHalfOpen, b.state)
+
+	// a slow request allowed while the circuit was closed is neither a probe nor closes the circuit
+	b.record(context.Background(), slow, nil)
+	assert.Equal(t, CircuitHalfOpen, b.state)
+	assert.Equal(t, 1, b.probes)
+	_, ok = b.allow()
+	assert.False(t, ok)
+
+	b.record(context.Background(), probe, nil)
+	assert.Equal(t, CircuitClosed, b.state)
+}
+
+func TestCircuitBreakerSkipsOpenPoolBackends(t *testing.T) {
+	var calls1, calls2 int32
+	ts1, ts2 := nearestServer(&calls1, http.StatusBadGateway), nearestServer(&calls2, http.StatusOK)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts1.URL, ts2.URL}, HealthCheckInterval: -1, MaxFailures: 100})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool, CircuitBreaker: &CircuitBreakerConfig{MinRequests: 1, Cooldown: time.Minute}})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.Error(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls1))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls2))
+}
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+	assert.Equal(t, "unknown", CircuitState(42).String())
+}
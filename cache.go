@@ -0,0 +1,260 @@
+package osrm
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// cacheKeyPrecision quantizes coordinates of cache keys to about a meter
+	cacheKeyPrecision = 1e5
+	// defaultCacheMaxAge is a time within which a data version change is noticed by default
+	defaultCacheMaxAge = time.Minute
+	// previousDataVersions is a number of replaced data versions remembered as outdated
+	previousDataVersions = 4
+	// defaultLRUCacheSize is a number of LRU cache entries if the size is not positive
+	defaultLRUCacheSize = 1000
+)
+
+// cachedServices lists services with cached responses
+var cachedServices = map[string]bool{
+	"route":   true,
+	"table":   true,
+	"nearest": true,
+	"match":   true,
+}
+
+// Cache stores successful responses by canonical request keys.
+// Responses are shallow copies sharing slices with the responses returned to callers,
+// so callers must not modify them. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	// Purge removes all entries, it is called when the OSRM data version changes
+	Purge()
+}
+
+// cacheEntry is a cached response with the data version it was computed for
+type cacheEntry struct {
+	response    interface{}
+	dataVersion string
+	stored      time.Time
+}
+
+// responseCache looks up responses of route, table, nearest and match calls in the cache
+// and purges it when OSRM data version changes
+type responseCache struct {
+	versionedCache
+}
+
+func newResponseCache(c Cache, maxAge time.Duration) *responseCache {
+	return &responseCache{versionedCache{cache: c, maxAge: maxAge}}
+}
+
+// intercept implements Interceptor
+func (c *responseCache) intercept(ctx context.Context, call *Call, invoke Invoker) error {
+	if !cachedServices[call.Service] || call.Response == nil {
+		return invoke(ctx, call)
+	}
+
+	key := cacheKey(call)
+	if v, ok := c.cache.Get(key); ok {
+		if e, ok := v.(cacheEntry); ok && e.dataVersion == c.currentDataVersion() && !c.expired(e.stored) {
+			out := reflect.ValueOf(call.Response).Elem()
+			if r := reflect.ValueOf(e.response); r.Type() == out.Type() {
+				out.Set(r)
+				call.cached = true
+				return nil
+			}
+		}
+	}
+
+	if err := invoke(ctx, call); err != nil {
+		return err
+	}
+
+	version := ""
+	if r, ok := call.Response.(interface{ dataVersion() string }); ok {
+		version = r.dataVersion()
+	}
+	if !c.updateDataVersion(version) {
+		return nil // a response of outdated data
+	}
+	c.cache.Set(key, cacheEntry{
+		response:    reflect.ValueOf(call.Response).Elem().Interface(),
+		dataVersion: version,
+		stored:      time.Now(),
+	})
+	return nil
+}
+
+// versionedCache is a cache purged when OSRM responds with a new data version.
+// Versions are only learned from responses and cache hits never notice a data change,
+// so entries older than the maximum age are requested again to learn the current version.
+type versionedCache struct {
+	cache Cache
+	// maxAge limits the age of used entries, entries are used until purged if it is not positive
+	maxAge time.Duration
+
+	mu          sync.RWMutex
+	dataVersion string
+	// previous are last versions replaced by the current one, servers lagging behind
+	// could still respond with them
+	previous []string
+}
+
+// expired reports whether an entry stored at the given time is too old to be used
+func (c *versionedCache) expired(stored time.Time) bool {
+	return c.maxAge > 0 && time.Since(stored) >= c.maxAge
+}
+
+func (c *versionedCache) currentDataVersion() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dataVersion
+}
+
+// updateDataVersion purges the cache if OSRM reports a data version not seen before.
+// It reports whether the version is the current one, a previous version is outdated.
+func (c *versionedCache) updateDataVersion(version string) bool {
+	if version == c.currentDataVersion() {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version == c.dataVersion {
+		return true
+	}
+	for _, v := range c.previous {
+		if v == version {
+			return false
+		}
+	}
+	c.previous = append(c.previous, c.dataVersion)
+	if len(c.previous) > previousDataVersions {
+		c.previous = c.previous[1:]
+	}
+	c.dataVersion = version
+	c.cache.Purge()
+	return true
+}
+
+// cacheKey returns a canonical key of the call: service, profile, quantized coordinates and sorted options
+func cacheKey(call *Call) string {
+	var b strings.Builder
+	b.WriteString(call.Service)
+	b.WriteByte('/')
+	b.WriteString(call.Profile)
+	b.WriteByte('/')
+	for i, p := range call.Coordinates.PointSet {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(quantize(p.Lng()))
+		b.WriteByte(',')
+		b.WriteString(quantize(p.Lat()))
+	}
+	if len(call.Options) > 0 {
+		b.WriteByte('?')
+		b.WriteString(options(call.Options).encode())
+	}
+	return b.String()
+}
+
+func quantize(v float64) string {
+	return strconv.FormatFloat(math.Round(v*cacheKeyPrecision)/cacheKeyPrecision, 'f', -1, 64)
+}
+
+// LRUCache is an in-memory cache evicting least recently used entries and entries older than TTL
+type LRUCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+}
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewLRUCache creates a cache of the given number of entries, 1000 entries are kept if size is not positive.
+// Entries do not expire if TTL is not positive.
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	if size <= 0 {
+		size = defaultLRUCacheSize
+	}
+	return &LRUCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Get implements Cache
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set implements Cache
+func (c *LRUCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Purge implements Cache
+func (c *LRUCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
+
+// Len returns a number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,193 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	geo "github.com/paulmach/go.geo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func versionedServer(calls *int32, version *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.URL.Query().Get("number") == "13" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code": "NoSegment", "message": "Could not find a matching segment"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"code": "Ok", "data_version": %q, "waypoints": [{"name": %q}]}`, version.Load(), r.URL.Path)
+	}))
+}
+
+func TestCacheResponses(t *testing.T) {
+	var calls int32
+	var version atomic.Value
+	version.Store("v1")
+	ts := versionedServer(&calls, &version)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Cache: NewLRUCache(10, time.Minute)})
+	nearest := func(lng, lat float64, number int) *NearestResponse {
+		r, err := osrm.Nearest(context.Background(), NearestRequest{
+			Profile:     "car",
+			Coordinates: NewGeometryFromPointSet(geo.PointSet{{lng, lat}}),
+			Number:      number,
+		})
+		require.NoError(t, err)
+		return r
+	}
+
+	r1 := nearest(-73.990185, 40.714701, 1)
+	r2 := nearest(-73.9901851, 40.7147009, 1)
+	assert.Equal(t, r1, r2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	nearest(-73.990185, 40.714701, 2)
+	nearest(-73.985746, 40.715655, 1)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// a new data version purges the cache
+	version.Store("v2")
+	nearest(-73.985746, 40.715655, 2)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	r := nearest(-73.990185, 40.714701, 1)
+	assert.Equal(t, "v2", r.DataVersion)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+	nearest(-73.990185, 40.714701, 1)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	// a server lagging behind with a previous version neither purges the cache nor fills it
+	version.Store("v1")
+	for i := 0; i < 2; i++ {
+		r = nearest(-73.985746, 40.715655, 1)
+		assert.Equal(t, "v1", r.DataVersion)
+	}
+	assert.Equal(t, int32(7), atomic.LoadInt32(&calls))
+	r = nearest(-73.990185, 40.714701, 1)
+	assert.Equal(t, "v2", r.DataVersion)
+	assert.Equal(t, int32(7), atomic.LoadInt32(&calls))
+}
+
+func TestCacheSkipsErrorsAndOtherServices(t *testing.T) {
+	var calls int32
+	var version atomic.Value
+	version.Store("v1")
+	ts := versionedServer(&calls, &version)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Cache: NewLRUCache(10, 0)})
+
+	for i := 0; i < 2; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry, Number: 13})
+		require.EqualError(t, err, "NoSegment - Could not find a matching segment")
+		_, err = osrm.Trip(context.Background(), TripRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestCacheKey(t *testing.T) {
+	call := &Call{
+		Service:     "route",
+		Profile:     "car",
+		Coordinates: NewGeometryFromPointSet(geo.PointSet{{-73.9901849, 40.714701}, {-73.985746, 40.7156551}}),
+		Options:     url.Values{"steps": {"true"}, "bearings": {"10,20", "30,40"}, "alternatives": {"2"}},
+	}
+	assert.Equal(t, "route/car/-73.99018,40.7147;-73.98575,40.71566?alternatives=2&bearings=10%2C20;30%2C40&steps=true", cacheKey(call))
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, _ = c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+
+	c.Set("a", 4)
+	v, _ = c.Get("a")
+	assert.Equal(t, 4, v)
+
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	c := NewLRUCache(2, 10*time.Millisecond)
+	c.Set("a", 1)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	var calls int32
+	var version atomic.Value
+	version.Store("v1")
+	ts := versionedServer(&calls, &version)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Cache: NewLRUCache(10, 0), CacheMaxAge: 20 * time.Millisecond})
+	nearest := func() *NearestResponse {
+		r, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+		return r
+	}
+
+	nearest()
+	version.Store("v2")
+	assert.Equal(t, "v1", nearest().DataVersion)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// an entry older than the maximum age is requested again and a new data version is noticed
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, "v2", nearest().DataVersion)
+	assert.Equal(t, "v2", nearest().DataVersion)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestVersionedCachePreviousVersions(t *testing.T) {
+	c := versionedCache{cache: NewLRUCache(10, 0)}
+	for i := 0; i < 10; i++ {
+		assert.True(t, c.updateDataVersion(fmt.Sprintf("v%d", i)))
+	}
+	assert.Len(t, c.previous, previousDataVersions)
+	assert.False(t, c.updateDataVersion("v8"))
+	assert.True(t, c.updateDataVersion("v9"))
+	// versions replaced long ago are forgotten
+	assert.True(t, c.updateDataVersion("v1"))
+	assert.Equal(t, "v1", c.currentDataVersion())
+}
+
+func TestLRUCacheDefaultSize(t *testing.T) {
+	c := NewLRUCache(0, 0)
+	for i := 0; i < defaultLRUCacheSize+1; i++ {
+		c.Set(fmt.Sprint(i), i)
+	}
+	assert.Equal(t, defaultLRUCacheSize, c.Len())
+	_, ok := c.Get("0")
+	assert.False(t, ok)
+	v, ok := c.Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+}
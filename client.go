@@ -2,11 +2,14 @@ package osrm
 
 import (
 	"context"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type (
@@ -21,51 +24,192 @@ type (
 	client struct {
 		httpClient HTTPClient
 		serverURL  string
+		retry      RetryPolicy
+		pool       *Pool
+		router     *Router
+		breakers   *breakers
+		hedger     *hedger
+		limiter    *Limiter
+		// maxResponseSize limits response bodies, no limit if not positive
+		maxResponseSize int64
 	}
 )
 
 // newClient creates a client with server url and specific getter
 func newClient(serverURL string, c HTTPClient) client {
-	return client{c, serverURL}
+	return client{httpClient: c, serverURL: serverURL}
 }
 
-// doRequest makes GET request to OSRM server and decodes the given JSON
+// doRequest makes GET request to OSRM server and decodes the given JSON or binary response.
+// Transient failures are retried according to the retry policy, every attempt picks
+// a server from the pool if it is set. Slow attempts are hedged according to the hedge policy.
 func (c client) doRequest(ctx context.Context, in *request, out interface{}) error {
-	url, err := in.URL(c.serverURL)
+	upstream, err := c.upstream(in.profile)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.get(ctx, url)
+	if _, err := in.URL(upstream.ServerURL); err != nil {
+		return err
+	}
+
+	pool, hedger := upstream.Pool, c.hedger
+	if pool == nil || len(pool.backends) < 2 {
+		hedger = nil // a duplicate would be sent to the same server
+	}
+	return c.retry.do(ctx, func(attemptCtx context.Context) error {
+		var primary atomic.Value // a pool server of the first request, avoided by its duplicate
+
+		return hedger.do(attemptCtx, in.service, out, func(attemptCtx context.Context, out interface{}, hedge bool) error {
+			// every request takes a turn of its own, a backoff or a hedge delay doesn't hold one
+			release, err := c.limiter.acquire(attemptCtx)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			if pool == nil {
+				return c.attemptServer(ctx, attemptCtx, upstream.ServerURL, in, out)
+			}
+
+			// a duplicate is only sent to another healthy server, unlike the first request
+			b, err := pool.acquire(func(b *Backend) bool {
+				return (!hedge || b != primary.Load()) && c.breakers.usable(b)
+			}, !hedge)
+			if err == errNoUsableServer {
+				return errHedgeSkipped
+			}
+			if err != nil {
+				return err
+			}
+			if !hedge {
+				primary.Store(b)
+			}
+			start := time.Now()
+			err = c.attemptServer(ctx, attemptCtx, b.url, in, out)
+			pool.release(ctx, attemptCtx, b, start, err)
+			return err
+		})
+	})
+}
+
+// attemptServer makes a single request to the server through its circuit breaker.
+// Attempt timeouts are failures of the server, unlike the caller's cancellation.
+func (c client) attemptServer(ctx, attemptCtx context.Context, serverURL string, in *request, out interface{}) error {
+	url, err := in.URL(serverURL)
 	if err != nil {
 		return err
 	}
-	defer closeSilently(resp.Body)
 
-	bytes, err := ioutil.ReadAll(resp.Body)
+	cb := c.breakers.get(serverURL)
+	generation, ok := cb.allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+	err = c.attempt(attemptCtx, url, in, out)
+	cb.record(ctx, generation, err)
+	return err
+}
+
+// upstream returns servers of the profile
+func (c client) upstream(profile string) (Upstream, error) {
+	if c.router == nil || profile == "" {
+		return Upstream{ServerURL: c.serverURL, Pool: c.pool}, nil
+	}
+	return c.router.route(profile)
+}
+
+// attempt makes a single GET request and decodes the response
+func (c client) attempt(ctx context.Context, url string, in *request, out interface{}) error {
+	var (
+		t          *tracer
+		statusCode int
+	)
+	if in.observe != nil {
+		t = newTracer()
+		ctx = t.context(ctx)
+		defer func() { in.observe(url, statusCode, t.stats()) }()
+	}
+
+	resp, err := c.get(ctx, url, in.header)
 	if err != nil {
-		return fmt.Errorf("failed to read body: %v", err)
+		return err
 	}
+	defer drainAndClose(resp.Body)
+	statusCode = resp.StatusCode
 
 	// OSRM returns both codes 200 and 400 in a case with a body.
 	// In other cases, it returns an unexpected error without a body.
 	// http://project-osrm.org/docs/v5.5.1/api/#responses
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
-		return fmt.Errorf("unexpected http status code %d with body %q", resp.StatusCode, bytes)
+		excerpt, truncated, _ := readExcerpt(resp.Body)
+		t.bodyRead(len(excerpt))
+		return &StatusError{StatusCode: resp.StatusCode, Body: excerpt, truncated: truncated}
 	}
 
-	if err := json.Unmarshal(bytes, out); err != nil {
-		return fmt.Errorf("failed to unmarshal body %q: %v", bytes, err)
+	body := newBodyReader(resp.Body, c.maxResponseSize)
+	defer func() { t.bodyRead(int(body.n)) }()
+
+	// Flatbuffers encoded errors are returned with 400 status code, but OSRM fails
+	// with JSON body if it could not even parse the request format
+	if in.format == formatFlatbuffers && !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		bytes, err := body.readAll()
+		if err != nil {
+			return err
+		}
+		t.bodyRead(len(bytes))
+		if err := unmarshalFlatbuffers(bytes, out); err != nil {
+			return fmt.Errorf("failed to unmarshal flatbuffers body: %v", err)
+		}
+		return nil
+	}
+
+	// Binary responses (e.g. vector tiles) are decoded by the response itself,
+	// errors are still returned as JSON
+	if u, ok := out.(encoding.BinaryUnmarshaler); ok && resp.StatusCode == http.StatusOK {
+		bytes, err := body.readAll()
+		if err != nil {
+			return err
+		}
+		t.bodyRead(len(bytes))
+		if err := u.UnmarshalBinary(bytes); err != nil {
+			return fmt.Errorf("failed to unmarshal binary body: %v", err)
+		}
+		return nil
+	}
+
+	// Responses with their own JSON decoders (e.g. large tables) get the whole body at once,
+	// so it is not scanned by encoding/json in advance
+	if u, ok := out.(json.Unmarshaler); ok {
+		bytes, release, err := body.readPooled()
+		if err != nil {
+			return err
+		}
+		defer release()
+		t.bodyRead(len(bytes))
+		if err := u.UnmarshalJSON(bytes); err != nil {
+			return fmt.Errorf("failed to unmarshal body %s: %v", body.quoted(), err)
+		}
+		return nil
+	}
+
+	// json.Decoder reads the whole value into its own buffer before decoding it,
+	// so responses without a custom decoder are kept in memory at once as well
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return body.failure(fmt.Errorf("failed to unmarshal body %s: %v", body.quoted(), err))
 	}
 
 	return nil
 }
 
-func (c client) get(ctx context.Context, url string) (*http.Response, error) {
+func (c client) get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	return c.httpClient.Do(req.WithContext(ctx))
 }
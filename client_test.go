@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,7 +13,7 @@ import (
 
 func Test_getWithError(t *testing.T) {
 	c := newClient("/", &http.Client{})
-	b, err := c.get(context.Background(), "/")
+	b, err := c.get(context.Background(), "/", nil)
 	require.Nil(t, b)
 	require.NotNil(t, err)
 }
@@ -47,5 +48,77 @@ func Test_doRequestWithBodyUnmarshalFailure(t *testing.T) {
 		service: "foobar",
 	}
 	err := c.doRequest(context.Background(), &req, nil)
-	require.EqualError(t, err, "failed to unmarshal body \"\": unexpected end of JSON input")
+	require.EqualError(t, err, "failed to unmarshal body \"\": EOF")
+}
+
+func Test_doRequestWithTruncatedBodyInError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(502)
+		fmt.Fprint(w, strings.Repeat("a", 2*maxExcerptSize))
+	}))
+	defer ts.Close()
+
+	c := newClient(ts.URL, &http.Client{})
+	req := request{
+		profile: "something",
+		coords:  geometry,
+		service: "foobar",
+	}
+	err := c.doRequest(context.Background(), &req, nil)
+	require.EqualError(t, err, fmt.Sprintf("unexpected http status code 502 with body %q...", strings.Repeat("a", maxExcerptSize)))
+	require.Len(t, err.(*StatusError).Body, maxExcerptSize)
+}
+
+func Test_doRequestWithInvalidJSONExcerpt(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": [`+strings.Repeat(`{"name": "x"},`, 100)+`}`)
+	}))
+	defer ts.Close()
+
+	c := newClient(ts.URL, &http.Client{})
+	req := request{
+		profile: "something",
+		coords:  geometry,
+		service: "foobar",
+	}
+	var out NearestResponse
+	err := c.doRequest(context.Background(), &req, &out)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), `failed to unmarshal body "{\"code\": \"Ok\"`), err.Error())
+	require.Contains(t, err.Error(), `"...: invalid character '}'`)
+	require.True(t, len(err.Error()) < 2*maxExcerptSize)
+}
+
+func Test_doRequestWithTooLargeBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": [`+strings.Repeat(`{"name": "x"},`, 100)+`{}]}`)
+	}))
+	defer ts.Close()
+
+	req := request{
+		profile: "something",
+		coords:  geometry,
+		service: "foobar",
+	}
+
+	c := newClient(ts.URL, &http.Client{})
+	c.maxResponseSize = 1000
+	var out NearestResponse
+	require.Equal(t, ErrResponseTooLarge, c.doRequest(context.Background(), &req, &out))
+
+	c.maxResponseSize = 2000
+	require.NoError(t, c.doRequest(context.Background(), &req, &out))
+	require.Len(t, out.Waypoints, 101)
+}
+
+func Test_doRequestWithTooLargeBinaryBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 100))
+	}))
+	defer ts.Close()
+
+	c := newClient(ts.URL, &http.Client{})
+	c.maxResponseSize = 50
+	var out tileResponse
+	require.Equal(t, ErrResponseTooLarge, c.doRequest(context.Background(), tileRequest("car", 13, 1, 2), &out))
 }
@@ -0,0 +1,152 @@
+package osrm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// coalescedServices lists services with coalesced calls
+var coalescedServices = map[string]bool{
+	"route":   true,
+	"table":   true,
+	"nearest": true,
+}
+
+// coalescer shares a single upstream call between concurrent identical calls.
+// Callers share the decoded response, so they must not modify it.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+// sharedCall is an upstream call shared by waiting callers
+type sharedCall struct {
+	done    chan struct{}
+	call    *Call
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{calls: map[string]*sharedCall{}}
+}
+
+// intercept implements Interceptor
+func (c *coalescer) intercept(ctx context.Context, call *Call, invoke Invoker) error {
+	if !coalescedServices[call.Service] || call.Response == nil {
+		return invoke(ctx, call)
+	}
+
+	key, err := coalesceKey(ctx, call)
+	if err != nil {
+		return invoke(ctx, call)
+	}
+
+	c.mu.Lock()
+	sc, ok := c.calls[key]
+	if !ok {
+		sc = c.start(ctx, key, call, invoke)
+		c.calls[key] = sc
+	}
+	sc.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-sc.done:
+	case <-ctx.Done():
+		c.leave(key, sc)
+		return ctx.Err()
+	}
+
+	reflect.ValueOf(call.Response).Elem().Set(reflect.ValueOf(sc.call.Response).Elem())
+	call.URL, call.StatusCode, call.Stats = sc.call.URL, sc.call.StatusCode, sc.call.Stats
+	call.Start, call.Duration = sc.call.Start, sc.call.Duration
+	return sc.err
+}
+
+// coalesceKey returns a key shared by identical calls: the URL, the headers and the priority of the call,
+// so the shared call is made with the same headers and limiter lane as every call sharing it
+func coalesceKey(ctx context.Context, call *Call) (string, error) {
+	url, err := call.sync().URL("")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(url)
+	b.WriteByte('\n')
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		b.WriteString("priority=" + string(p))
+	}
+	b.WriteByte('\n')
+	if err := call.Header.Write(&b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// start invokes a copy of the call detached from the caller's cancellation,
+// it is canceled once all callers leave
+func (c *coalescer) start(ctx context.Context, key string, call *Call, invoke Invoker) *sharedCall {
+	req := *call.request
+	shared := &Call{
+		Service:     call.Service,
+		Profile:     call.Profile,
+		Coordinates: call.Coordinates,
+		Options:     call.Options,
+		Header:      call.Header,
+		Response:    reflect.New(reflect.TypeOf(call.Response).Elem()).Interface(),
+		request:     &req,
+	}
+
+	ctx, cancel := context.WithCancel(detachedContext{ctx})
+	sc := &sharedCall{done: make(chan struct{}), call: shared, cancel: cancel}
+
+	go func() {
+		defer cancel()
+		sc.err = invoke(ctx, shared)
+
+		c.mu.Lock()
+		if c.calls[key] == sc {
+			delete(c.calls, key)
+		}
+		c.mu.Unlock()
+		close(sc.done)
+	}()
+	return sc
+}
+
+// leave cancels the shared call if the caller was the last one waiting for it
+func (c *coalescer) leave(key string, sc *sharedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sc.waiters--
+	if sc.waiters == 0 {
+		sc.cancel()
+		if c.calls[key] == sc {
+			delete(c.calls, key)
+		}
+	}
+}
+
+// detachedContext keeps values of the parent context, but not its deadline and cancellation.
+// It hides the stats target of the caller, stats of the shared call are copied to every caller.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	if _, ok := key.(callStatsKey); ok {
+		return nil
+	}
+	return c.parent.Value(key)
+}
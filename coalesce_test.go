@@ -0,0 +1,202 @@
+package osrm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingInvoker returns an invoker waiting for the release and counting its calls
+func blockingInvoker(calls *int32, release chan struct{}, ctxErr *atomic.Value) Invoker {
+	return func(ctx context.Context, call *Call) error {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		if ctxErr != nil && ctx.Err() != nil {
+			ctxErr.Store(ctx.Err())
+		}
+		call.Response.(*NearestResponse).Waypoints = []NearestWaypoint{{Name: call.Profile}}
+		return ctx.Err()
+	}
+}
+
+func nearestCall(profile string) *Call {
+	return newCall(NearestRequest{Profile: profile, Coordinates: geometry}.request(), &NearestResponse{})
+}
+
+func (c *coalescer) waiters(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sc, ok := c.calls[key]; ok {
+		return sc.waiters
+	}
+	return 0
+}
+
+func TestCoalescerSharesCall(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := newCoalescer()
+	invoke := blockingInvoker(&calls, release, nil)
+	key, _ := coalesceKey(context.Background(), nearestCall("car"))
+
+	var wg sync.WaitGroup
+	responses := make([]*NearestResponse, 5)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			call := nearestCall("car")
+			assert.NoError(t, c.intercept(context.Background(), call, invoke))
+			responses[i] = call.Response.(*NearestResponse)
+		}(i)
+	}
+
+	waitFor(t, func() bool { return c.waiters(key) == 5 })
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, r := range responses {
+		assert.Equal(t, "car", r.Waypoints[0].Name)
+	}
+	assert.Empty(t, c.calls)
+}
+
+func TestCoalescerHonoursCallerContexts(t *testing.T) {
+	var calls int32
+	var sharedErr atomic.Value
+	release := make(chan struct{})
+	c := newCoalescer()
+	invoke := blockingInvoker(&calls, release, &sharedErr)
+	key, _ := coalesceKey(context.Background(), nearestCall("car"))
+
+	done := make(chan error)
+	go func() {
+		done <- c.intercept(context.Background(), nearestCall("car"), invoke)
+	}()
+	waitFor(t, func() bool { return c.waiters(key) == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- c.intercept(ctx, nearestCall("car"), invoke)
+	}()
+	waitFor(t, func() bool { return c.waiters(key) == 2 })
+
+	cancel()
+	require.Equal(t, context.Canceled, <-done)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Nil(t, sharedErr.Load())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCoalescerCancelsAbandonedCall(t *testing.T) {
+	var calls int32
+	var sharedErr atomic.Value
+	c := newCoalescer()
+	invoke := blockingInvoker(&calls, make(chan struct{}), &sharedErr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, c.intercept(ctx, nearestCall("car"), invoke))
+
+	waitFor(t, func() bool { return sharedErr.Load() != nil })
+	assert.Equal(t, context.Canceled, sharedErr.Load())
+	assert.Empty(t, c.calls)
+}
+
+func TestCoalescerSeparatesCalls(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	close(release)
+	c := newCoalescer()
+	invoke := blockingInvoker(&calls, release, nil)
+
+	car, bike := nearestCall("car"), nearestCall("bike")
+	require.NoError(t, c.intercept(context.Background(), car, invoke))
+	require.NoError(t, c.intercept(context.Background(), bike, invoke))
+	assert.Equal(t, "bike", bike.Response.(*NearestResponse).Waypoints[0].Name)
+
+	withHeader := nearestCall("car")
+	withHeader.Header = http.Header{"Authorization": {"token"}}
+	require.NoError(t, c.intercept(context.Background(), withHeader, invoke))
+	require.NoError(t, c.intercept(WithPriority(context.Background(), PriorityBatch), nearestCall("car"), invoke))
+
+	trip := newCall(TripRequest{Profile: "car", Coordinates: geometry}.request(), &TripResponse{})
+	require.NoError(t, c.intercept(context.Background(), trip, func(ctx context.Context, call *Call) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestCoalesceKey(t *testing.T) {
+	key := func(ctx context.Context, header http.Header) string {
+		call := nearestCall("car")
+		call.Header = header
+		k, err := coalesceKey(ctx, call)
+		require.NoError(t, err)
+		return k
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, key(ctx, http.Header{"A": {"1"}, "B": {"2"}}), key(ctx, http.Header{"B": {"2"}, "A": {"1"}}))
+	assert.NotEqual(t, key(ctx, nil), key(ctx, http.Header{"A": {"1"}}))
+	assert.NotEqual(t, key(ctx, nil), key(WithPriority(ctx, PriorityBatch), nil))
+}
+
+func TestCoalescerHidesCallerStats(t *testing.T) {
+	c := newCoalescer()
+	var stats CallStats
+	call := nearestCall("car")
+	err := c.intercept(WithCallStats(context.Background(), &stats), call, func(ctx context.Context, call *Call) error {
+		assert.Nil(t, ctx.Value(callStatsKey{}))
+		call.Stats.Attempts = 1
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, call.Stats.Attempts)
+}
+
+func TestCoalesceRequests(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		_, _ = w.Write([]byte(`{"code": "Ok", "waypoints": [{"name": "shared"}]}`))
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, CoalesceRequests: true})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+			if assert.NoError(t, err) {
+				assert.Equal(t, "shared", r.Waypoints[0].Name)
+			}
+		}()
+	}
+
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 1 })
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
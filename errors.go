@@ -1,6 +1,9 @@
 package osrm
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+)
 
 // Error codes that could be returned from OSRM
 const (
@@ -15,6 +18,8 @@ const (
 	ErrorCodeNoRoute        = "NoRoute"
 	ErrorCodeNoTable        = "NoTable"
 	ErrorCodeNoMatch        = "NoMatch"
+	ErrorCodeNoTrips        = "NoTrips"
+	ErrorCodeNotImplemented = "NotImplemented"
 	errorCodeOK             = "Ok" // "Ok" error code never returned to library client, thus not exported
 )
 
@@ -24,3 +29,18 @@ var (
 	ErrNoCoordinates    = errors.New("osrm5: the request should contain coordinates")
 	ErrEmptyServiceName = errors.New("osrm5: the request should contain a service name")
 )
+
+// ErrEmptyPool is returned for requests to a pool without servers
+var ErrEmptyPool = errors.New("osrm5: the pool has no servers")
+
+// ErrResponseTooLarge is returned when a response body exceeds the maximum response size
+var ErrResponseTooLarge = errors.New("osrm5: response body is too large")
+
+// UnknownProfileError is returned when a router has no server for the request profile
+type UnknownProfileError struct {
+	Profile string
+}
+
+func (e *UnknownProfileError) Error() string {
+	return "osrm5: unknown profile " + strconv.Quote(e.Profile)
+}
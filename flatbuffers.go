@@ -0,0 +1,542 @@
+package osrm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	geo "github.com/paulmach/go.geo"
+)
+
+// Responses in flatbuffers format are decoded according to the schema of osrm-backend
+// (include/engine/api/flatbuffers/*.fbs). Field slots below follow declaration order of the schema:
+//
+//	table FBResult { error: bool; code: Error; data_version: string; waypoints: [Waypoint]; routes: [RouteObject]; table: Table; }
+//	table Error { code: string; message: string; }
+//	table Waypoint { hint: string; distance: float; name: string; location: Position; nodes: Uint64Pair;
+//	                 matchings_index: uint; waypoint_index: uint; alternatives_count: uint; trips_index: uint; }
+//	table RouteObject { distance: float; duration: float; weight: float; weight_name: string; confidence: float;
+//	                    polyline: string; coordinates: [Position]; legs: [Leg]; }
+//	table Leg { distance: double; duration: double; weight: double; summary: string; annotations: Annotation; steps: [Step]; }
+//	table Annotation { distance: [uint]; duration: [uint]; datasources: [uint]; nodes: [ulong]; weight: [uint];
+//	                   speed: [float]; metadata: Metadata; }
+//	table Metadata { datasource_names: [string]; }
+//	table Step { distance: float; duration: float; polyline: string; coordinates: [Position]; weight: float;
+//	             name: string; ref: string; pronunciation: string; destinations: string; exits: string; mode: string;
+//	             maneuver: StepManeuver; intersections: [Intersection]; rotary_name: string;
+//	             rotary_pronunciation: string; driving_side: bool; }
+//	table StepManeuver { location: Position; bearing_before: ushort; bearing_after: ushort; type: ManeuverType;
+//	                     modifier: Turn; exit: ubyte; }
+//	table Intersection { location: Position; bearings: [short]; classes: [string]; entry: [bool];
+//	                     in_bearing: uint; out_bearing: uint; lanes: [Lane]; }
+//	table Lane { indications: [Turn]; valid: bool; }
+//	table Table { durations: [float]; rows: ushort; cols: ushort; distances: [float]; destinations: [Waypoint];
+//	              fallback_speed_cells: [uint]; }
+//	struct Position { longitude: float; latitude: float; }
+//	struct Uint64Pair { first: ulong; second: ulong; }
+
+// formatFlatbuffers is an OSRM URL format suffix for flatbuffers responses
+const formatFlatbuffers = "flatbuffers"
+
+// fbManeuverTypes maps values of the ManeuverType enum of the schema
+var fbManeuverTypes = []ManeuverType{
+	ManeuverTurn, ManeuverNewName, ManeuverDepart, ManeuverArrive, ManeuverMerge, ManeuverOnRamp,
+	ManeuverOffRamp, ManeuverFork, ManeuverEndOfRoad, ManeuverContinue, ManeuverRoundabout,
+	ManeuverRotary, ManeuverRoundaboutTurn, ManeuverNotification, ManeuverExitRoundabout, ManeuverExitRotary,
+}
+
+// fbTurns maps values of the Turn enum of the schema, the first value is None
+var fbTurns = []LaneIndication{
+	LaneNone, LaneUTurn, LaneSharpRight, LaneRight, LaneSlightRight,
+	LaneStraight, LaneSlightLeft, LaneLeft, LaneSharpLeft,
+}
+
+var errFlatbuffersOutOfRange = errors.New("offset is out of range")
+
+// flatbuffersResponse is implemented by responses which could be decoded from flatbuffers format
+type flatbuffersResponse interface {
+	response
+	unmarshalFlatbuffers(result fbTable) error
+}
+
+// unmarshalFlatbuffers decodes FBResult root table into the response
+func unmarshalFlatbuffers(data []byte, out interface{}) (err error) {
+	r, ok := out.(flatbuffersResponse)
+	if !ok {
+		return fmt.Errorf("%T could not be decoded from flatbuffers", out)
+	}
+
+	// out of range reads panic with errFlatbuffersOutOfRange to keep decoders free of bounds checks
+	defer func() {
+		if p := recover(); p != nil {
+			if p != errFlatbuffersOutOfRange {
+				panic(p)
+			}
+			err = errFlatbuffersOutOfRange
+		}
+	}()
+
+	buf := fbBuffer(data)
+	return r.unmarshalFlatbuffers(buf.table(int(buf.uint32(0))))
+}
+
+// fbBuffer is a flatbuffers encoded message
+type fbBuffer []byte
+
+func (b fbBuffer) bytes(pos, n int) []byte {
+	if pos < 0 || n < 0 || pos+n > len(b) {
+		panic(errFlatbuffersOutOfRange)
+	}
+	return b[pos : pos+n]
+}
+
+func (b fbBuffer) uint8(pos int) uint8 {
+	return b.bytes(pos, 1)[0]
+}
+
+func (b fbBuffer) uint16(pos int) uint16 {
+	return binary.LittleEndian.Uint16(b.bytes(pos, 2))
+}
+
+func (b fbBuffer) uint32(pos int) uint32 {
+	return binary.LittleEndian.Uint32(b.bytes(pos, 4))
+}
+
+func (b fbBuffer) uint64(pos int) uint64 {
+	return binary.LittleEndian.Uint64(b.bytes(pos, 8))
+}
+
+func (b fbBuffer) float32(pos int) float32 {
+	return math.Float32frombits(b.uint32(pos))
+}
+
+// indirect follows an offset stored at the given position
+func (b fbBuffer) indirect(pos int) int {
+	return pos + int(b.uint32(pos))
+}
+
+func (b fbBuffer) string(pos int) string {
+	pos = b.indirect(pos)
+	return string(b.bytes(pos+4, int(b.uint32(pos))))
+}
+
+func (b fbBuffer) table(pos int) fbTable {
+	return fbTable{buf: b, pos: pos, vtable: pos - int(int32(b.uint32(pos)))}
+}
+
+// position decodes Position struct
+func (b fbBuffer) position(pos int) geo.Point {
+	return geo.Point{float64(b.float32(pos)), float64(b.float32(pos + 4))}
+}
+
+// fbTable is a view of a flatbuffers table
+type fbTable struct {
+	buf         fbBuffer
+	pos, vtable int
+}
+
+// field returns an absolute position of the field or 0 if the field is not set
+func (t fbTable) field(slot int) int {
+	o := 4 + 2*slot
+	if o >= int(t.buf.uint16(t.vtable)) {
+		return 0
+	}
+	if off := int(t.buf.uint16(t.vtable + o)); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t fbTable) has(slot int) bool {
+	return t.field(slot) != 0
+}
+
+func (t fbTable) bool(slot int) bool {
+	return t.uint8(slot) != 0
+}
+
+func (t fbTable) uint8(slot int) uint8 {
+	if p := t.field(slot); p != 0 {
+		return t.buf.uint8(p)
+	}
+	return 0
+}
+
+func (t fbTable) uint16(slot int) uint16 {
+	if p := t.field(slot); p != 0 {
+		return t.buf.uint16(p)
+	}
+	return 0
+}
+
+func (t fbTable) uint32(slot int) uint32 {
+	if p := t.field(slot); p != 0 {
+		return t.buf.uint32(p)
+	}
+	return 0
+}
+
+func (t fbTable) float32(slot int) float32 {
+	if p := t.field(slot); p != 0 {
+		return t.buf.float32(p)
+	}
+	return 0
+}
+
+func (t fbTable) float64(slot int) float64 {
+	if p := t.field(slot); p != 0 {
+		return math.Float64frombits(t.buf.uint64(p))
+	}
+	return 0
+}
+
+func (t fbTable) string(slot int) string {
+	if p := t.field(slot); p != 0 {
+		return t.buf.string(p)
+	}
+	return ""
+}
+
+func (t fbTable) position(slot int) geo.Point {
+	if p := t.field(slot); p != 0 {
+		return t.buf.position(p)
+	}
+	return geo.Point{}
+}
+
+func (t fbTable) table(slot int) (fbTable, bool) {
+	if p := t.field(slot); p != 0 {
+		return t.buf.table(t.buf.indirect(p)), true
+	}
+	return fbTable{}, false
+}
+
+// vector returns a position of the first element of the vector and its length
+func (t fbTable) vector(slot int) (int, int) {
+	if p := t.field(slot); p != 0 {
+		p = t.buf.indirect(p)
+		n := int(t.buf.uint32(p))
+		// every element takes at least one byte, so a valid length never exceeds the buffer
+		t.buf.bytes(p+4, n)
+		return p + 4, n
+	}
+	return 0, 0
+}
+
+func (t fbTable) tables(slot int) []fbTable {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	tables := make([]fbTable, n)
+	for i := range tables {
+		tables[i] = t.buf.table(t.buf.indirect(pos + 4*i))
+	}
+	return tables
+}
+
+func (t fbTable) strings(slot int) []string {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	s := make([]string, n)
+	for i := range s {
+		s[i] = t.buf.string(pos + 4*i)
+	}
+	return s
+}
+
+func (t fbTable) uint32s(slot int) []uint32 {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint32, n)
+	for i := range v {
+		v[i] = t.buf.uint32(pos + 4*i)
+	}
+	return v
+}
+
+// uint32sAsFloats decodes a vector of uint as float32 values
+func (t fbTable) uint32sAsFloats(slot int) []float32 {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = float32(t.buf.uint32(pos + 4*i))
+	}
+	return v
+}
+
+func (t fbTable) uint64s(slot int) []uint64 {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint64, n)
+	for i := range v {
+		v[i] = t.buf.uint64(pos + 8*i)
+	}
+	return v
+}
+
+func (t fbTable) float32s(slot int) []float32 {
+	pos, n := t.vector(slot)
+	if n == 0 {
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = t.buf.float32(pos + 4*i)
+	}
+	return v
+}
+
+// geometry decodes either an encoded polyline or a vector of positions
+func (t fbTable) geometry(polylineSlot, coordinatesSlot int) Geometry {
+	if t.has(polylineSlot) {
+		return NewGeometryFromPath(*geo.NewPathFromEncoding(t.string(polylineSlot), polyline6Factor))
+	}
+	pos, n := t.vector(coordinatesSlot)
+	ps := make(geo.PointSet, n)
+	for i := range ps {
+		ps[i] = t.buf.position(pos + 8*i)
+	}
+	return NewGeometryFromPointSet(ps)
+}
+
+// fbResponseStatus decodes code, message and data version of FBResult
+func fbResponseStatus(result fbTable) ResponseStatus {
+	status := ResponseStatus{Code: errorCodeOK, DataVersion: result.string(2)}
+	if result.bool(0) {
+		code, _ := result.table(1)
+		status.Code = code.string(0)
+		status.Message = code.string(1)
+	}
+	return status
+}
+
+func fbWaypoint(t fbTable) Waypoint {
+	return Waypoint{
+		Hint:     t.string(0),
+		Distance: t.float32(1),
+		Name:     t.string(2),
+		Location: t.position(3),
+	}
+}
+
+func fbWaypoints(tables []fbTable) []Waypoint {
+	if tables == nil {
+		return nil
+	}
+	waypoints := make([]Waypoint, len(tables))
+	for i, t := range tables {
+		waypoints[i] = fbWaypoint(t)
+	}
+	return waypoints
+}
+
+func fbRoutes(tables []fbTable) []Route {
+	if tables == nil {
+		return nil
+	}
+	routes := make([]Route, len(tables))
+	for i, t := range tables {
+		routes[i] = fbRoute(t)
+	}
+	return routes
+}
+
+func fbRoute(t fbTable) Route {
+	route := Route{
+		Distance:   t.float32(0),
+		Duration:   t.float32(1),
+		Wieght:     t.float32(2),
+		WeightName: t.string(3),
+		Geometry:   t.geometry(5, 6),
+	}
+	for _, leg := range t.tables(7) {
+		route.Legs = append(route.Legs, fbLeg(leg))
+	}
+	return route
+}
+
+func fbLeg(t fbTable) RouteLeg {
+	leg := RouteLeg{
+		Distance: float32(t.float64(0)),
+		Duration: float32(t.float64(1)),
+		Weight:   float32(t.float64(2)),
+		Summary:  t.string(3),
+	}
+	if a, ok := t.table(4); ok {
+		leg.Annotation = fbAnnotation(a)
+	}
+	for _, step := range t.tables(5) {
+		leg.Steps = append(leg.Steps, fbStep(step))
+	}
+	return leg
+}
+
+func fbAnnotation(t fbTable) Annotation {
+	a := Annotation{
+		Distance:    t.uint32sAsFloats(0),
+		Duration:    t.uint32sAsFloats(1),
+		Datasources: t.uint32s(2),
+		Nodes:       t.uint64s(3),
+		Weight:      t.uint32sAsFloats(4),
+		Speed:       t.float32s(5),
+	}
+	if m, ok := t.table(6); ok {
+		a.Metadata = &AnnotationMetadata{DatasourceNames: m.strings(0)}
+	}
+	return a
+}
+
+func fbStep(t fbTable) RouteStep {
+	step := RouteStep{
+		Distance:            t.float32(0),
+		Duration:            t.float32(1),
+		Geometry:            t.geometry(2, 3),
+		Weight:              t.float32(4),
+		Name:                t.string(5),
+		Ref:                 t.string(6),
+		Pronunciation:       t.string(7),
+		Destinations:        t.string(8),
+		Exits:               t.string(9),
+		Mode:                t.string(10),
+		RotaryName:          t.string(13),
+		RotaryPronunciation: t.string(14),
+		DrivingSide:         "right",
+	}
+	if t.bool(15) {
+		step.DrivingSide = "left"
+	}
+	if m, ok := t.table(11); ok {
+		step.Maneuver = fbManeuver(m)
+	}
+	for _, i := range t.tables(12) {
+		step.Intersections = append(step.Intersections, fbIntersection(i))
+	}
+	return step
+}
+
+func fbManeuver(t fbTable) StepManeuver {
+	m := StepManeuver{
+		Location:      t.position(0),
+		BearingBefore: float32(t.uint16(1)),
+		BearingAfter:  float32(t.uint16(2)),
+	}
+	if typ := int(t.uint8(3)); typ < len(fbManeuverTypes) {
+		m.Type = fbManeuverTypes[typ]
+	}
+	if mod := int(t.uint8(4)); mod > 0 && mod < len(fbTurns) {
+		m.Modifier = ManeuverModifier(fbTurns[mod])
+	}
+	if t.has(5) {
+		exit := uint32(t.uint8(5))
+		m.Exit = &exit
+	}
+	return m
+}
+
+func fbIntersection(t fbTable) Intersection {
+	i := Intersection{
+		Location: t.position(0),
+		Classes:  t.strings(2),
+	}
+	pos, n := t.vector(1)
+	for k := 0; k < n; k++ {
+		i.Bearings = append(i.Bearings, t.buf.uint16(pos+2*k))
+	}
+	pos, n = t.vector(3)
+	for k := 0; k < n; k++ {
+		i.Entry = append(i.Entry, t.buf.uint8(pos+k) != 0)
+	}
+	if t.has(4) {
+		in := t.uint32(4)
+		i.In = &in
+	}
+	if t.has(5) {
+		out := t.uint32(5)
+		i.Out = &out
+	}
+	for _, l := range t.tables(6) {
+		lane := Lane{Valid: l.bool(1)}
+		pos, n := l.vector(0)
+		for k := 0; k < n; k++ {
+			if ind := int(t.buf.uint8(pos + k)); ind < len(fbTurns) {
+				lane.Indications = append(lane.Indications, fbTurns[ind])
+			}
+		}
+		i.Lanes = append(i.Lanes, lane)
+	}
+	return i
+}
+
+func (r *RouteResponse) unmarshalFlatbuffers(result fbTable) error {
+	r.ResponseStatus = fbResponseStatus(result)
+	r.Waypoints = fbWaypoints(result.tables(3))
+	r.Routes = fbRoutes(result.tables(4))
+	return nil
+}
+
+func (r *TripResponse) unmarshalFlatbuffers(result fbTable) error {
+	r.ResponseStatus = fbResponseStatus(result)
+	r.Trips = fbRoutes(result.tables(4))
+	for _, t := range result.tables(3) {
+		r.Waypoints = append(r.Waypoints, TripWaypoint{
+			Waypoint:      fbWaypoint(t),
+			WaypointIndex: int(t.uint32(6)),
+			TripsIndex:    int(t.uint32(8)),
+		})
+	}
+	return nil
+}
+
+func (r *MatchResponse) unmarshalFlatbuffers(result fbTable) error {
+	r.ResponseStatus = fbResponseStatus(result)
+	for _, t := range result.tables(4) {
+		r.Matchings = append(r.Matchings, Matching{
+			Route:      fbRoute(t),
+			Confidence: float64(t.float32(4)),
+			Geometry:   t.geometry(5, 6),
+		})
+	}
+	for _, t := range result.tables(3) {
+		// unmatched tracepoints are encoded without location
+		if !t.has(3) {
+			r.Tracepoints = append(r.Tracepoints, nil)
+			continue
+		}
+		r.Tracepoints = append(r.Tracepoints, &Tracepoint{
+			Location:          t.position(3),
+			Hint:              t.string(0),
+			MatchingIndex:     int(t.uint32(5)),
+			Index:             int(t.uint32(6)),
+			AlternativesCount: int(t.uint32(7)),
+		})
+	}
+	return nil
+}
+
+func (r *NearestResponse) unmarshalFlatbuffers(result fbTable) error {
+	r.ResponseStatus = fbResponseStatus(result)
+	for _, t := range result.tables(3) {
+		w := NearestWaypoint{
+			Location: t.position(3),
+			Distance: float64(t.float32(1)),
+			Name:     t.string(2),
+			Hint:     t.string(0),
+		}
+		if p := t.field(4); p != 0 {
+			w.Nodes = []uint64{t.buf.uint64(p), t.buf.uint64(p + 8)}
+		}
+		r.Waypoints = append(r.Waypoints, w)
+	}
+	return nil
+}
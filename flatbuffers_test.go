@@ -0,0 +1,357 @@
+package osrm
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+
+	geo "github.com/paulmach/go.geo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fbObject describes a flatbuffers table of a fixture as field values by slots
+type fbObject map[int]interface{}
+
+// fbPosition and fbUint64Pair represent structs of the schema
+type (
+	fbPosition   [2]float32
+	fbUint64Pair [2]uint64
+)
+
+// fbBuilder encodes fixtures front to back: every table is followed by its children
+type fbBuilder struct {
+	buf []byte
+}
+
+func buildFlatbuffers(obj fbObject) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	root := b.table(obj)
+	binary.LittleEndian.PutUint32(b.buf, uint32(root))
+	return b.buf
+}
+
+func (b *fbBuilder) uint16(v uint16) {
+	b.buf = append(b.buf, byte(v), byte(v>>8))
+}
+
+func (b *fbBuilder) uint32(v uint32) {
+	b.buf = append(b.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (b *fbBuilder) uint64(v uint64) {
+	b.uint32(uint32(v))
+	b.uint32(uint32(v >> 32))
+}
+
+func (b *fbBuilder) table(obj fbObject) int {
+	slots := make([]int, 0, len(obj))
+	for slot := range obj {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+
+	vtable := len(b.buf)
+	vtableSize := 4
+	if len(slots) > 0 {
+		vtableSize += 2 * (slots[len(slots)-1] + 1)
+	}
+	b.buf = append(b.buf, make([]byte, vtableSize)...)
+	table := len(b.buf)
+	b.uint32(uint32(table - vtable))
+
+	var refs []int
+	for _, slot := range slots {
+		binary.LittleEndian.PutUint16(b.buf[vtable+4+2*slot:], uint16(len(b.buf)-table))
+		switch v := obj[slot].(type) {
+		case bool:
+			if v {
+				b.buf = append(b.buf, 1)
+			} else {
+				b.buf = append(b.buf, 0)
+			}
+		case uint8:
+			b.buf = append(b.buf, v)
+		case uint16:
+			b.uint16(v)
+		case uint32:
+			b.uint32(v)
+		case float32:
+			b.uint32(math.Float32bits(v))
+		case float64:
+			b.uint64(math.Float64bits(v))
+		case fbPosition:
+			b.uint32(math.Float32bits(v[0]))
+			b.uint32(math.Float32bits(v[1]))
+		case fbUint64Pair:
+			b.uint64(v[0])
+			b.uint64(v[1])
+		default:
+			refs = append(refs, slot, len(b.buf))
+			b.uint32(0)
+		}
+	}
+	binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(vtableSize))
+	binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(len(b.buf)-table))
+
+	for i := 0; i < len(refs); i += 2 {
+		b.ref(refs[i+1], obj[refs[i]])
+	}
+	return table
+}
+
+// ref writes the value and stores its offset at the given position
+func (b *fbBuilder) ref(pos int, v interface{}) {
+	offset := b.value(v) - pos
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(offset))
+}
+
+func (b *fbBuilder) value(v interface{}) int {
+	pos := len(b.buf)
+	switch v := v.(type) {
+	case string:
+		b.uint32(uint32(len(v)))
+		b.buf = append(append(b.buf, v...), 0)
+	case fbObject:
+		return b.table(v)
+	case []fbObject:
+		b.uint32(uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, obj := range v {
+			b.ref(pos+4+4*i, obj)
+		}
+	case []string:
+		b.uint32(uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, s := range v {
+			b.ref(pos+4+4*i, s)
+		}
+	case []uint8:
+		b.uint32(uint32(len(v)))
+		b.buf = append(b.buf, v...)
+	case []uint16:
+		b.uint32(uint32(len(v)))
+		for _, n := range v {
+			b.uint16(n)
+		}
+	case []uint32:
+		b.uint32(uint32(len(v)))
+		for _, n := range v {
+			b.uint32(n)
+		}
+	case []uint64:
+		b.uint32(uint32(len(v)))
+		for _, n := range v {
+			b.uint64(n)
+		}
+	case []float32:
+		b.uint32(uint32(len(v)))
+		for _, f := range v {
+			b.uint32(math.Float32bits(f))
+		}
+	case []fbPosition:
+		b.uint32(uint32(len(v)))
+		for _, p := range v {
+			b.uint32(math.Float32bits(p[0]))
+			b.uint32(math.Float32bits(p[1]))
+		}
+	default:
+		panic("unsupported fixture value")
+	}
+	return pos
+}
+
+func fbWaypointFixture(name string, lon, lat float32) fbObject {
+	return fbObject{0: "hint-" + name, 1: float32(1.5), 2: name, 3: fbPosition{lon, lat}}
+}
+
+func TestUnmarshalFlatbuffersRoute(t *testing.T) {
+	data := buildFlatbuffers(fbObject{
+		2: "2017-11-17T21:43:02Z",
+		3: []fbObject{
+			fbWaypointFixture("first", -73.990195, 40.714703),
+			fbWaypointFixture("second", -73.985746, 40.715655),
+		},
+		4: []fbObject{{
+			0: float32(1190.5),
+			1: float32(92.2),
+			2: float32(93.5),
+			3: "routability",
+			5: "w{_tlAnb_clCfEz@zJf@",
+			7: []fbObject{{
+				0: float64(637.5),
+				1: float64(58),
+				2: float64(59),
+				3: "Broadway",
+				4: fbObject{
+					0: []uint32{12, 20},
+					1: []uint32{1, 3},
+					2: []uint32{0, 1},
+					3: []uint64{4294967297, 4294967298, 4294967299},
+					5: []float32{8.5, 9},
+					6: fbObject{0: []string{"lua profile", "traffic"}},
+				},
+				5: []fbObject{{
+					0:  float32(33.1),
+					1:  float32(5),
+					3:  []fbPosition{{-73.9902, 40.7147}, {-73.99025, 40.71441}},
+					5:  "Broadway",
+					6:  "US 9",
+					10: "driving",
+					11: fbObject{
+						0: fbPosition{-73.9902, 40.7147},
+						2: uint16(195),
+						3: uint8(10),
+						4: uint8(4),
+						5: uint8(2),
+					},
+					12: []fbObject{{
+						0: fbPosition{-73.9902, 40.7147},
+						1: []uint16{15, 195},
+						2: []string{"toll"},
+						3: []uint8{0, 1},
+						5: uint32(1),
+						6: []fbObject{{0: []uint8{7, 5}, 1: true}},
+					}},
+					15: true,
+				}},
+			}},
+		}},
+	})
+
+	var r RouteResponse
+	require.NoError(t, unmarshalFlatbuffers(data, &r))
+
+	assert.Equal(t, ResponseStatus{Code: "Ok", DataVersion: "2017-11-17T21:43:02Z"}, r.ResponseStatus)
+	require.Len(t, r.Waypoints, 2)
+	assert.Equal(t, "hint-first", r.Waypoints[0].Hint)
+	assert.Equal(t, "second", r.Waypoints[1].Name)
+	assert.InDelta(t, -73.985746, r.Waypoints[1].Location.Lng(), 1e-5)
+	assert.InDelta(t, 40.715655, r.Waypoints[1].Location.Lat(), 1e-5)
+
+	require.Len(t, r.Routes, 1)
+	route := r.Routes[0]
+	assert.Equal(t, float32(1190.5), route.Distance)
+	assert.Equal(t, float32(92.2), route.Duration)
+	assert.Equal(t, "routability", route.WeightName)
+	assert.Equal(t, 3, route.Geometry.Length())
+
+	require.Len(t, route.Legs, 1)
+	leg := route.Legs[0]
+	assert.Equal(t, float32(637.5), leg.Distance)
+	assert.Equal(t, "Broadway", leg.Summary)
+	assert.Equal(t, []float32{12, 20}, leg.Annotation.Distance)
+	assert.Equal(t, []float32{1, 3}, leg.Annotation.Duration)
+	assert.Equal(t, []uint64{4294967297, 4294967298, 4294967299}, leg.Annotation.Nodes)
+	assert.Equal(t, []float32{8.5, 9}, leg.Annotation.Speed)
+	assert.Equal(t, "traffic", leg.Annotation.DatasourceName(1))
+
+	require.Len(t, leg.Steps, 1)
+	step := leg.Steps[0]
+	assert.Equal(t, float32(33.1), step.Distance)
+	assert.Equal(t, "US 9", step.Ref)
+	assert.Equal(t, "driving", step.Mode)
+	assert.Equal(t, "left", step.DrivingSide)
+	assert.Equal(t, 2, step.Geometry.Length())
+	assert.Equal(t, ManeuverRoundabout, step.Maneuver.Type)
+	assert.Equal(t, ModifierSlightRight, step.Maneuver.Modifier)
+	assert.Equal(t, float32(195), step.Maneuver.BearingAfter)
+	require.NotNil(t, step.Maneuver.Exit)
+	assert.Equal(t, uint32(2), *step.Maneuver.Exit)
+
+	require.Len(t, step.Intersections, 1)
+	intersection := step.Intersections[0]
+	assert.Equal(t, []uint16{15, 195}, intersection.Bearings)
+	assert.Equal(t, []string{"toll"}, intersection.Classes)
+	assert.Equal(t, []bool{false, true}, intersection.Entry)
+	assert.Nil(t, intersection.In)
+	require.NotNil(t, intersection.Out)
+	assert.Equal(t, uint32(1), *intersection.Out)
+	assert.Equal(t, []Lane{{Indications: []LaneIndication{LaneLeft, LaneStraight}, Valid: true}}, intersection.Lanes)
+}
+
+func TestUnmarshalFlatbuffersMatch(t *testing.T) {
+	data := buildFlatbuffers(fbObject{
+		3: []fbObject{
+			{0: "hint", 3: fbPosition{1, 2}, 5: uint32(0), 6: uint32(0), 7: uint32(3)},
+			{},
+			{3: fbPosition{3, 4}, 5: uint32(0), 6: uint32(1)},
+		},
+		4: []fbObject{{
+			0: float32(1035.3),
+			4: float32(0.5),
+			5: "w{_tlAnb_clCfEz@zJf@",
+			7: []fbObject{{}},
+		}},
+	})
+
+	var r MatchResponse
+	require.NoError(t, unmarshalFlatbuffers(data, &r))
+
+	require.Len(t, r.Matchings, 1)
+	assert.Equal(t, 0.5, r.Matchings[0].Confidence)
+	assert.Equal(t, float32(1035.3), r.Matchings[0].Distance)
+	assert.Equal(t, 3, r.Matchings[0].Geometry.Length())
+
+	require.Len(t, r.Tracepoints, 3)
+	assert.Equal(t, &Tracepoint{Hint: "hint", Location: geo.Point{1, 2}, AlternativesCount: 3}, r.Tracepoints[0])
+	assert.Nil(t, r.Tracepoints[1])
+	assert.Equal(t, &Tracepoint{Index: 1, Location: geo.Point{3, 4}}, r.Tracepoints[2])
+}
+
+func TestUnmarshalFlatbuffersNearest(t *testing.T) {
+	data := buildFlatbuffers(fbObject{
+		3: []fbObject{{0: "hint", 1: float32(4.5), 2: "Broadway", 3: fbPosition{1, 2}, 4: fbUint64Pair{42, 43}}},
+	})
+
+	var r NearestResponse
+	require.NoError(t, unmarshalFlatbuffers(data, &r))
+
+	assert.Equal(t, []NearestWaypoint{{
+		Location: geo.Point{1, 2},
+		Distance: 4.5,
+		Name:     "Broadway",
+		Hint:     "hint",
+		Nodes:    []uint64{42, 43},
+	}}, r.Waypoints)
+}
+
+func TestUnmarshalFlatbuffersTrip(t *testing.T) {
+	data := buildFlatbuffers(fbObject{
+		3: []fbObject{
+			{3: fbPosition{1, 2}, 6: uint32(1), 8: uint32(0)},
+			{3: fbPosition{3, 4}, 6: uint32(0), 8: uint32(0)},
+		},
+		4: []fbObject{{0: float32(1869.5)}},
+	})
+
+	var r TripResponse
+	require.NoError(t, unmarshalFlatbuffers(data, &r))
+
+	require.Len(t, r.Trips, 1)
+	assert.Equal(t, float32(1869.5), r.Trips[0].Distance)
+	require.Len(t, r.Waypoints, 2)
+	assert.Equal(t, 1, r.Waypoints[0].WaypointIndex)
+	assert.Equal(t, geo.Point{3, 4}, r.Waypoints[1].Location)
+}
+
+func TestUnmarshalFlatbuffersError(t *testing.T) {
+	data := buildFlatbuffers(fbObject{
+		0: true,
+		1: fbObject{0: "NoRoute", 1: "Impossible route between points"},
+	})
+
+	var r RouteResponse
+	require.NoError(t, unmarshalFlatbuffers(data, &r))
+	assert.EqualError(t, r.apiError(), "NoRoute - Impossible route between points")
+}
+
+func TestUnmarshalFlatbuffersMalformed(t *testing.T) {
+	data := buildFlatbuffers(fbObject{2: "2017-11-17T21:43:02Z"})
+
+	var r RouteResponse
+	assert.Equal(t, errFlatbuffersOutOfRange, unmarshalFlatbuffers(data[:len(data)-8], &r))
+	assert.Equal(t, errFlatbuffersOutOfRange, unmarshalFlatbuffers(nil, &r))
+	assert.Error(t, unmarshalFlatbuffers(data, &tileResponse{}))
+}
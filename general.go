@@ -0,0 +1,59 @@
+package osrm
+
+import "strings"
+
+// GeneralOptions represents options supported by all OSRM services.
+// Per-coordinate options (bearings, radiuses, hints and approaches) should either be empty
+// or have exactly one element for each coordinate of the request.
+// See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md#general-options for details.
+type GeneralOptions struct {
+	Bearings   []Bearing
+	Radiuses   []Radius
+	Hints      []string
+	Approaches []Approach
+	// Exclude lists classes of roads to be avoided, e.g. "toll" or "motorway"
+	Exclude       []string
+	Snapping      Snapping
+	GenerateHints GenerateHints
+	SkipWaypoints SkipWaypoints
+}
+
+// withBearings returns the options with the given bearings unless the options have their own.
+// It supports Bearings fields which requests had before GeneralOptions.
+func (o GeneralOptions) withBearings(bearings []Bearing) GeneralOptions {
+	if len(o.Bearings) == 0 {
+		o.Bearings = bearings
+	}
+	return o
+}
+
+// withHints returns the options with the given hints unless the options have their own
+func (o GeneralOptions) withHints(hints []string) GeneralOptions {
+	if len(o.Hints) == 0 {
+		o.Hints = hints
+	}
+	return o
+}
+
+// apply adds general options to the given options
+func (o GeneralOptions) apply(opts options) options {
+	if len(o.Bearings) > 0 {
+		opts.set("bearings", bearings(o.Bearings))
+	}
+	for _, r := range o.Radiuses {
+		opts.add("radiuses", r.String())
+	}
+	if len(o.Hints) > 0 {
+		opts.add("hints", o.Hints...)
+	}
+	for _, a := range o.Approaches {
+		opts.add("approaches", a.String())
+	}
+	if len(o.Exclude) > 0 {
+		opts.set("exclude", strings.Join(o.Exclude, ","))
+	}
+	return opts.
+		setStringer("snapping", o.Snapping).
+		setStringer("generate_hints", o.GenerateHints).
+		setStringer("skip_waypoints", o.SkipWaypoints)
+}
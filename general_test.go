@@ -0,0 +1,66 @@
+package osrm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmptyGeneralOptions(t *testing.T) {
+	assert.Empty(t, GeneralOptions{}.apply(options{}).encode())
+}
+
+func TestGeneralOptions(t *testing.T) {
+	opts := GeneralOptions{
+		Bearings:      []Bearing{{60, 380}, {45, 180}},
+		Radiuses:      []Radius{10.5, RadiusUnlimited, RadiusDefault},
+		Hints:         []string{"", "b"},
+		Approaches:    []Approach{ApproachCurb, ApproachDefault},
+		Exclude:       []string{"toll", "motorway"},
+		Snapping:      SnappingAny,
+		GenerateHints: GenerateHintsFalse,
+		SkipWaypoints: SkipWaypointsTrue,
+	}
+	assert.Equal(
+		t,
+		"approaches=curb;&bearings=60%2C380%3B45%2C180&exclude=toll%2Cmotorway&generate_hints=false"+
+			"&hints=;b&radiuses=10.5;unlimited;&skip_waypoints=true&snapping=any",
+		opts.apply(options{}).encode())
+}
+
+func TestDeprecatedBearings(t *testing.T) {
+	bearings := []Bearing{{60, 380}}
+	assert.Equal(t, "bearings=60%2C380&geometries=polyline6", RouteRequest{Bearings: bearings}.request().options.encode())
+	assert.Equal(t, "bearings=60%2C380", NearestRequest{Bearings: bearings}.request().options.encode())
+	assert.Equal(t, "bearings=45%2C90", NearestRequest{
+		Bearings:       bearings,
+		GeneralOptions: GeneralOptions{Bearings: []Bearing{{45, 90}}},
+	}.request().options.encode())
+}
+
+func TestNewRadiuses(t *testing.T) {
+	assert.Equal(t, []Radius{10, RadiusDefault, RadiusUnlimited}, NewRadiuses(10, 0, -1))
+	assert.Empty(t, NewRadiuses())
+}
+
+func TestGeneralOptionsForEveryService(t *testing.T) {
+	opts := GeneralOptions{
+		Approaches: []Approach{ApproachCurb, ApproachUnrestricted},
+	}
+	cases := []struct {
+		name        string
+		request     *request
+		expectedURI string
+	}{
+		{"route", RouteRequest{GeneralOptions: opts}.request(), "approaches=curb;unrestricted&geometries=polyline6"},
+		{"table", TableRequest{GeneralOptions: opts}.request(), "approaches=curb;unrestricted"},
+		{"match", MatchRequest{GeneralOptions: opts}.request(), "approaches=curb;unrestricted&geometries=polyline6"},
+		{"nearest", NearestRequest{GeneralOptions: opts}.request(), "approaches=curb;unrestricted"},
+		{"trip", TripRequest{GeneralOptions: opts}.request(), "approaches=curb;unrestricted&geometries=polyline6"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expectedURI, c.request.options.encode())
+		})
+	}
+}
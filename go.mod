@@ -1,7 +1,9 @@
 module github.com/gojuno/go.osrm
 
+go 1.11
+
 require (
 	github.com/paulmach/go.geo v0.0.0-20180829195134-22b514266d33
-	github.com/paulmach/go.geojson v1.4.0 // indirect
+	github.com/paulmach/go.geojson v1.4.0
 	github.com/stretchr/testify v1.3.0
 )
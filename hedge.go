@@ -0,0 +1,198 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errHedgeSkipped is returned by a duplicate request which could not be sent
+var errHedgeSkipped = errors.New("osrm5: no server for a duplicate request")
+
+const (
+	defaultHedgeRatio = 0.1
+	maxHedgeTokens    = 10
+	latencySamples    = 100
+	minLatencySamples = 20
+)
+
+// HedgePolicy defines when a duplicate of a slow request is sent to another server.
+// The first answer is used and the other request is canceled. Duplicates are sent to other
+// servers of a pool only, so requests are not hedged without a pool of several servers.
+type HedgePolicy struct {
+	// Delay is a time to wait for an answer before a duplicate request is sent.
+	// It is used until enough latencies are observed if a percentile is set.
+	// Requests are not hedged while there is no delay, so without a percentile
+	// a zero delay disables hedging.
+	Delay time.Duration
+	// Percentile (e.g. 0.95) of observed latencies is used as a delay if set, values above 1 are used as 1
+	Percentile float64
+	// Services lists hedged services (e.g. "table" and "match"), all services are hedged if not set
+	Services []string
+	// MaxRatio caps duplicate requests to the ratio of all hedged requests, 0.1 is used if not set
+	MaxRatio float64
+}
+
+// hedger sends duplicates of slow requests within the budget
+type hedger struct {
+	policy HedgePolicy
+
+	mu      sync.Mutex
+	tokens  float64
+	samples []time.Duration
+	next    int
+}
+
+func newHedger(policy HedgePolicy) *hedger {
+	if policy.MaxRatio <= 0 {
+		policy.MaxRatio = defaultHedgeRatio
+	}
+	if policy.Percentile > 1 {
+		policy.Percentile = 1
+	}
+	// a single duplicate is allowed before the budget fills up
+	return &hedger{policy: policy, tokens: 1}
+}
+
+// do calls send and, if it does not answer in time, its duplicate with hedge flag set.
+// Every call decodes its own response, the first successful one is copied into out.
+func (h *hedger) do(ctx context.Context, service string, out interface{}, send func(ctx context.Context, out interface{}, hedge bool) error) error {
+	if h == nil || out == nil || !h.hedges(service) {
+		return send(ctx, out, false)
+	}
+
+	start := time.Now()
+	h.deposit()
+	delay, ok := h.delay()
+	if !ok {
+		// latencies are still observed to find out the delay
+		err := send(ctx, out, false)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		out interface{}
+		err error
+	}
+	results := make(chan result, 2)
+	run := func(hedge bool) {
+		o := reflect.New(reflect.TypeOf(out).Elem()).Interface()
+		results <- result{o, send(ctx, o, hedge)}
+	}
+
+	go run(false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var (
+		firstErr error
+		pending  = 1
+		timeout  = timer.C
+	)
+	for {
+		select {
+		case <-timeout:
+			timeout = nil
+			if h.withdraw() {
+				pending++
+				go run(true)
+			}
+		case r := <-results:
+			pending--
+			if r.err == errHedgeSkipped {
+				h.refund()
+				if pending == 0 {
+					return firstErr
+				}
+				continue
+			}
+			if r.err == nil {
+				h.observe(time.Since(start))
+				reflect.ValueOf(out).Elem().Set(reflect.ValueOf(r.out).Elem())
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 {
+				return firstErr
+			}
+		}
+	}
+}
+
+func (h *hedger) hedges(service string) bool {
+	if len(h.policy.Services) == 0 {
+		return true
+	}
+	for _, s := range h.policy.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// deposit adds a share of a duplicate request to the budget
+func (h *hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens = math.Min(h.tokens+h.policy.MaxRatio, maxHedgeTokens)
+}
+
+// withdraw takes a duplicate request from the budget
+func (h *hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// refund returns a duplicate request which was not sent to the budget
+func (h *hedger) refund() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens = math.Min(h.tokens+1, maxHedgeTokens)
+}
+
+func (h *hedger) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < latencySamples {
+		h.samples = append(h.samples, latency)
+		return
+	}
+	h.samples[h.next] = latency
+	h.next = (h.next + 1) % latencySamples
+}
+
+// delay returns a time to wait before a duplicate request, false if it is not known yet
+func (h *hedger) delay() (time.Duration, bool) {
+	if h.policy.Percentile <= 0 {
+		return h.policy.Delay, h.policy.Delay > 0
+	}
+
+	h.mu.Lock()
+	samples := append([]time.Duration(nil), h.samples...)
+	h.mu.Unlock()
+
+	if len(samples) < minLatencySamples {
+		return h.policy.Delay, h.policy.Delay > 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[int(h.policy.Percentile*float64(len(samples)-1))], true
+}
@@ -0,0 +1,205 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgedRequest(t *testing.T) {
+	canceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	var calls int32
+	fast := nearestServer(&calls, http.StatusOK)
+	defer fast.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{slow.URL, fast.URL}, HealthCheckInterval: -1})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool, HedgePolicy: &HedgePolicy{Delay: 10 * time.Millisecond}})
+
+	start := time.Now()
+	r, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, errorCodeOK, r.Code)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow request is not canceled")
+	}
+}
+
+func TestHedgedRequestSkipsUnavailableServers(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer slow.Close()
+	var calls int32
+	ejected := nearestServer(&calls, http.StatusOK)
+	defer ejected.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{slow.URL, ejected.URL}, HealthCheckInterval: -1})
+	defer pool.Close()
+	atomic.StoreInt64(&pool.Backends()[1].ejectedUntil, time.Now().Add(time.Minute).UnixNano())
+
+	hedger := newHedger(HedgePolicy{Delay: time.Millisecond})
+	osrm := NewWithConfig(Config{Pool: pool})
+	osrm.client.hedger = hedger
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	assert.True(t, hedger.withdraw(), "the skipped duplicate is returned to the budget")
+}
+
+func TestHedgerBudget(t *testing.T) {
+	h := newHedger(HedgePolicy{Delay: time.Millisecond, MaxRatio: 0.5})
+
+	var hedges int32
+	send := func(ctx context.Context, out interface{}, hedge bool) error {
+		if hedge {
+			atomic.AddInt32(&hedges, 1)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Millisecond):
+		}
+		return nil
+	}
+
+	for i := 0; i < 10; i++ {
+		var out NearestResponse
+		require.NoError(t, h.do(context.Background(), "nearest", &out, send))
+	}
+
+	// a duplicate per two requests after the initial one
+	assert.Equal(t, int32(6), atomic.LoadInt32(&hedges))
+}
+
+func TestHedgerReturnsFirstSuccess(t *testing.T) {
+	h := newHedger(HedgePolicy{Delay: time.Millisecond})
+
+	var out NearestResponse
+	err := h.do(context.Background(), "nearest", &out, func(ctx context.Context, out interface{}, hedge bool) error {
+		if !hedge {
+			<-ctx.Done()
+			return errors.New("primary failed")
+		}
+		out.(*NearestResponse).Code = errorCodeOK
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, errorCodeOK, out.Code)
+
+	err = h.do(context.Background(), "nearest", &out, func(ctx context.Context, out interface{}, hedge bool) error {
+		return fmt.Errorf("hedge %v failed", hedge)
+	})
+	require.EqualError(t, err, "hedge false failed")
+}
+
+func TestHedgerServices(t *testing.T) {
+	h := newHedger(HedgePolicy{Services: []string{"table", "match"}})
+
+	assert.True(t, h.hedges("table"))
+	assert.True(t, h.hedges("match"))
+	assert.False(t, h.hedges("route"))
+	assert.True(t, newHedger(HedgePolicy{}).hedges("route"))
+
+	var hedged bool
+	var out RouteResponse
+	require.NoError(t, h.do(context.Background(), "route", &out, func(ctx context.Context, out interface{}, hedge bool) error {
+		hedged = hedged || hedge
+		return nil
+	}))
+	assert.False(t, hedged)
+}
+
+func TestHedgerPercentileDelay(t *testing.T) {
+	h := newHedger(HedgePolicy{Delay: time.Second, Percentile: 0.9})
+
+	for i := 1; i < minLatencySamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	delay, ok := h.delay()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	for i := minLatencySamples; i <= 2*latencySamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Len(t, h.samples, latencySamples)
+	delay, ok = h.delay()
+	assert.True(t, ok)
+	assert.Equal(t, 190*time.Millisecond, delay)
+}
+
+func TestHedgerPercentileAboveOne(t *testing.T) {
+	h := newHedger(HedgePolicy{Percentile: 95})
+	for i := 1; i <= minLatencySamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	delay, ok := h.delay()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(minLatencySamples)*time.Millisecond, delay)
+}
+
+func TestHedgerWithoutDelay(t *testing.T) {
+	h := newHedger(HedgePolicy{Percentile: 0.5})
+
+	var hedged bool
+	send := func(ctx context.Context, out interface{}, hedge bool) error {
+		hedged = hedged || hedge
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	for i := 0; i < minLatencySamples; i++ {
+		var out NearestResponse
+		require.NoError(t, h.do(context.Background(), "nearest", &out, send))
+	}
+	assert.False(t, hedged)
+
+	// the delay is known once enough latencies are observed
+	_, ok := h.delay()
+	assert.True(t, ok)
+	_, ok = newHedger(HedgePolicy{}).delay()
+	assert.False(t, ok)
+}
+
+func TestHedgingRequiresAnotherServer(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts.URL}, HealthCheckInterval: -1})
+	defer pool.Close()
+
+	for _, cfg := range []Config{{ServerURL: ts.URL}, {Pool: pool}} {
+		cfg.HedgePolicy = &HedgePolicy{Delay: time.Millisecond}
+		_, err := NewWithConfig(cfg).Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,201 @@
+package osrm
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// hintedServices lists services with reused hints
+var hintedServices = map[string]bool{
+	"route":   true,
+	"table":   true,
+	"match":   true,
+	"nearest": true,
+}
+
+// hintEntry is a stored hint with the data version it was returned for
+type hintEntry struct {
+	hint        string
+	dataVersion string
+}
+
+// hintStore records hints returned for coordinates and fills them in later calls of the same profile.
+// Calls with their own hints, bearings or radiuses are left intact since their snapping differs.
+type hintStore struct {
+	versionedCache
+}
+
+func newHintStore(c Cache) *hintStore {
+	return &hintStore{versionedCache{cache: c}}
+}
+
+// intercept implements Interceptor
+func (h *hintStore) intercept(ctx context.Context, call *Call, invoke Invoker) error {
+	if !hintedServices[call.Service] || call.Response == nil || constrainedSnapping(call) {
+		return invoke(ctx, call)
+	}
+
+	filled := h.fill(call)
+	err := invoke(ctx, call)
+	if len(filled) > 0 && rejectedHints(err) {
+		// the filled hints are likely outdated, they are dropped and the call is repeated without them
+		for _, i := range filled {
+			h.cache.Set(hintKey(call, i), hintEntry{})
+		}
+		delete(call.Options, "hints")
+		out := reflect.ValueOf(call.Response).Elem()
+		out.Set(reflect.Zero(out.Type()))
+		err = invoke(ctx, call)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.record(call)
+	return nil
+}
+
+// fill sets stored hints of the call coordinates and returns indices of the coordinates with hints.
+// Dropped hints are stored as empty ones, since caches could not delete entries.
+func (h *hintStore) fill(call *Call) []int {
+	version := h.currentDataVersion()
+	hints := make([]string, call.Coordinates.Length())
+	var filled []int
+	for i := range hints {
+		if v, ok := h.cache.Get(hintKey(call, i)); ok {
+			if e, ok := v.(hintEntry); ok && e.hint != "" && e.dataVersion == version {
+				hints[i] = e.hint
+				filled = append(filled, i)
+			}
+		}
+	}
+	if len(filled) == 0 {
+		return nil
+	}
+
+	if call.Options == nil {
+		call.Options = url.Values{}
+	}
+	call.Options["hints"] = hints
+	return filled
+}
+
+// record stores hints of the call response
+func (h *hintStore) record(call *Call) {
+	version := ""
+	if r, ok := call.Response.(interface{ dataVersion() string }); ok {
+		version = r.dataVersion()
+	}
+	if !h.updateDataVersion(version) {
+		return // hints of outdated data
+	}
+
+	for i, hint := range responseHints(call) {
+		if hint != "" {
+			h.cache.Set(hintKey(call, i), hintEntry{hint: hint, dataVersion: version})
+		}
+	}
+}
+
+// responseHints returns hints of the call response by coordinate indices
+func responseHints(call *Call) map[int]string {
+	n := call.Coordinates.Length()
+	hints := map[int]string{}
+	switch r := call.Response.(type) {
+	case *RouteResponse:
+		// waypoints could be limited to leg boundaries, they are matched to coordinates only if there are all of them
+		if len(r.Waypoints) == n {
+			for i, w := range r.Waypoints {
+				hints[i] = w.Hint
+			}
+		}
+	case *TableResponse:
+		sources, destinations := callIndices(call, "sources"), callIndices(call, "destinations")
+		if len(r.Sources) == len(sources) {
+			for i, w := range r.Sources {
+				hints[sources[i]] = w.Hint
+			}
+		}
+		if len(r.Destinations) == len(destinations) {
+			for i, w := range r.Destinations {
+				hints[destinations[i]] = w.Hint
+			}
+		}
+	case *MatchResponse:
+		for i, tp := range r.Tracepoints {
+			if tp != nil && i < n {
+				hints[i] = tp.Hint
+			}
+		}
+	case *NearestResponse:
+		if len(r.Waypoints) > 0 && n > 0 {
+			hints[0] = r.Waypoints[0].Hint
+		}
+	}
+	return hints
+}
+
+// callIndices returns table sources or destinations of the call, all coordinates if not set
+func callIndices(call *Call, key string) []int {
+	n := call.Coordinates.Length()
+	var indices []int
+	for _, v := range call.Options[key] {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= n {
+			return nil
+		}
+		indices = append(indices, i)
+	}
+	return tableIndices(indices, n)
+}
+
+// hintKey returns a key of the call coordinate: profile, quantized coordinate and options affecting snapping
+func hintKey(call *Call, i int) string {
+	p := call.Coordinates.PointSet[i]
+	var b strings.Builder
+	b.WriteString(call.Profile)
+	b.WriteByte('/')
+	b.WriteString(quantize(p.Lng()))
+	b.WriteByte(',')
+	b.WriteString(quantize(p.Lat()))
+
+	opts := options{}
+	for _, k := range []string{"exclude", "snapping"} {
+		if v, ok := call.Options[k]; ok {
+			opts[k] = v
+		}
+	}
+	if len(opts) > 0 {
+		b.WriteByte('?')
+		b.WriteString(opts.encode())
+	}
+	return b.String()
+}
+
+// constrainedSnapping reports whether the call has its own hints or constraints of snapping
+func constrainedSnapping(call *Call) bool {
+	for _, k := range []string{"hints", "bearings", "radiuses"} {
+		if len(call.Options[k]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectedHints reports whether the error could be caused by invalid hints
+func rejectedHints(err error) bool {
+	switch e := err.(type) {
+	case ResponseStatus:
+		switch e.Code {
+		case ErrorCodeInvalidQuery, ErrorCodeInvalidOptions, ErrorCodeInvalidValue:
+			return true
+		}
+	case *StatusError:
+		return e.StatusCode == http.StatusBadRequest
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hintServer returns waypoints with "<version>-<lng>" hints and records hints of requests.
+// It rejects hints of other data versions.
+type hintServer struct {
+	*httptest.Server
+	version atomic.Value
+
+	mu    sync.Mutex
+	hints []string
+}
+
+func newHintServer() *hintServer {
+	s := &hintServer{}
+	s.version.Store("v1")
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := s.version.Load().(string)
+		hints := ""
+		for _, param := range strings.Split(r.URL.RawQuery, "&") {
+			if strings.HasPrefix(param, "hints=") {
+				hints = strings.TrimPrefix(param, "hints=")
+			}
+		}
+		s.mu.Lock()
+		s.hints = append(s.hints, hints)
+		s.mu.Unlock()
+
+		for _, h := range strings.Split(hints, ";") {
+			if h != "" && !strings.HasPrefix(h, version) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code": "InvalidOptions", "message": "Hints are invalid"}`)
+				return
+			}
+		}
+
+		var waypoints []string
+		for _, p := range requestPoints(r) {
+			waypoints = append(waypoints, fmt.Sprintf(`{"hint": "%s-%v"}`, version, p.Lng()))
+		}
+		fmt.Fprintf(w, `{"code": "Ok", "data_version": %q, "waypoints": [%s]}`, version, strings.Join(waypoints, ","))
+	}))
+	return s
+}
+
+func (s *hintServer) requestedHints() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.hints...)
+}
+
+func TestHintStoreReusesHints(t *testing.T) {
+	ts := newHintServer()
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, HintStore: NewLRUCache(100, time.Minute)})
+	route := func(profile string, lngs ...float64) {
+		_, err := osrm.Route(context.Background(), RouteRequest{Profile: profile, Coordinates: tableCoordinates(lngs...)})
+		require.NoError(t, err)
+	}
+
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: tableCoordinates(1)})
+	require.NoError(t, err)
+	route("car", 1, 2)
+	route("car", 2, 1.000001, 3)
+	route("bike", 1, 2)
+	assert.Equal(t, []string{"", "v1-1;", "v1-2;v1-1;", ""}, ts.requestedHints())
+}
+
+func TestHintStoreDataVersion(t *testing.T) {
+	ts := newHintServer()
+	defer ts.Close()
+
+	hints := NewLRUCache(100, time.Minute)
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, HintStore: hints})
+	route := func(lngs ...float64) {
+		_, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: tableCoordinates(lngs...)})
+		require.NoError(t, err)
+	}
+
+	route(1, 2)
+	assert.Equal(t, 2, hints.Len())
+
+	// a new data version drops stored hints
+	ts.version.Store("v2")
+	route(3, 4)
+	route(1, 2)
+	route(1, 2)
+	assert.Equal(t, []string{"", "", "", "v2-1;v2-2"}, ts.requestedHints())
+	assert.Equal(t, 4, hints.Len())
+}
+
+func TestHintStoreRetriesRejectedHints(t *testing.T) {
+	ts := newHintServer()
+	defer ts.Close()
+
+	hints := NewLRUCache(100, time.Minute)
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, HintStore: hints})
+	route := func(lngs ...float64) *RouteResponse {
+		r, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: tableCoordinates(lngs...)})
+		require.NoError(t, err)
+		return r
+	}
+
+	route(1, 2)
+	ts.version.Store("v2")
+	r := route(1, 2)
+	assert.Equal(t, "v2", r.DataVersion)
+	assert.Equal(t, []string{"", "v1-1;v1-2", ""}, ts.requestedHints())
+
+	route(1, 2)
+	assert.Equal(t, []string{"", "v1-1;v1-2", "", "v2-1;v2-2"}, ts.requestedHints())
+}
+
+func TestHintStoreDropsRejectedHintsOnly(t *testing.T) {
+	h := newHintStore(NewLRUCache(100, time.Minute))
+	var calls int32
+	invoke := func(ctx context.Context, call *Call) error {
+		atomic.AddInt32(&calls, 1)
+		if len(call.Options["hints"]) > 0 || call.Coordinates.GetAt(0).Lng() == 13 {
+			return ResponseStatus{Code: ErrorCodeInvalidQuery}
+		}
+		call.Response.(*NearestResponse).Waypoints = []NearestWaypoint{{Hint: "h"}}
+		return nil
+	}
+	nearest := func(lng float64) error {
+		call := newCall(NearestRequest{Profile: "car", Coordinates: tableCoordinates(lng)}.request(), &NearestResponse{})
+		return h.intercept(context.Background(), call, invoke)
+	}
+
+	require.NoError(t, nearest(1))
+	require.NoError(t, nearest(2))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// only the rejected hint of the first coordinate is dropped before the retry
+	require.NoError(t, nearest(1))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	v, _ := h.cache.Get(hintKey(newCall(NearestRequest{Profile: "car", Coordinates: tableCoordinates(2)}.request(), nil), 0))
+	assert.Equal(t, "h", v.(hintEntry).hint)
+
+	// a call without stored hints is not retried
+	require.Error(t, nearest(13))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestHintStoreSkipsConstrainedCalls(t *testing.T) {
+	ts := newHintServer()
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, HintStore: NewLRUCache(100, time.Minute)})
+	for _, opts := range []GeneralOptions{
+		{},
+		{Bearings: []Bearing{{0, 20}, {0, 20}}},
+		{Radiuses: []Radius{5, 5}},
+		{Hints: []string{"v1-own", ""}},
+		{Exclude: []string{"toll"}},
+	} {
+		_, err := osrm.Route(context.Background(), RouteRequest{
+			GeneralOptions: opts,
+			Profile:        "car",
+			Coordinates:    tableCoordinates(1, 2),
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"", "", "", "v1-own;", ""}, ts.requestedHints())
+}
+
+func TestResponseHints(t *testing.T) {
+	table := newCall(TableRequest{
+		Profile:      "car",
+		Coordinates:  tableCoordinates(1, 2, 3),
+		Sources:      []int{2},
+		Destinations: []int{0, 1},
+	}.request(), &TableResponse{
+		Sources:      []Waypoint{{Hint: "c"}},
+		Destinations: []Waypoint{{Hint: "a"}, {Hint: "b"}},
+	})
+	assert.Equal(t, map[int]string{0: "a", 1: "b", 2: "c"}, responseHints(table))
+
+	match := newCall(MatchRequest{Profile: "car", Coordinates: tableCoordinates(1, 2, 3)}.request(), &MatchResponse{
+		Tracepoints: []*Tracepoint{{Hint: "a"}, nil, {Hint: "c"}},
+	})
+	assert.Equal(t, map[int]string{0: "a", 2: "c"}, responseHints(match))
+
+	route := newCall(RouteRequest{Profile: "car", Coordinates: tableCoordinates(1, 2, 3), Waypoints: []int{0, 2}}.request(), &RouteResponse{
+		Waypoints: []Waypoint{{Hint: "a"}, {Hint: "c"}},
+	})
+	assert.Empty(t, responseHints(route))
+}
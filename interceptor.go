@@ -0,0 +1,101 @@
+package osrm
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Call represents a call of an OSRM method passed through interceptors.
+// Interceptors could modify the request fields before invoking the call.
+type Call struct {
+	Service     string
+	Profile     string
+	Coordinates Geometry
+	Options     url.Values
+	// Header is added to http requests of the call
+	Header http.Header
+
+	// Response points to the response the call is decoded into. An interceptor
+	// short-circuiting the call should fill it instead of invoking the call.
+	Response interface{}
+
+	// URL, StatusCode and Stats describe the last http request of the call,
+	// they are set once the call is invoked
+	URL        string
+	StatusCode int
+	Stats      CallStats
+	// Start and Duration are the timing of the invoked call
+	Start    time.Time
+	Duration time.Duration
+
+	request *request
+	// cached is set when the call is answered from the response cache
+	cached bool
+	mu     sync.Mutex
+	done   bool
+}
+
+// Invoker invokes a call
+type Invoker func(ctx context.Context, call *Call) error
+
+// Interceptor intercepts a call, it could modify the call, invoke it or short-circuit it
+// with an error or a response. Interceptors see OSRM API errors as errors returned by the invoker.
+type Interceptor func(ctx context.Context, call *Call, invoke Invoker) error
+
+// newCall creates a call of the request decoded into the response
+func newCall(in *request, out response) *Call {
+	return &Call{
+		Service:     in.service,
+		Profile:     in.profile,
+		Coordinates: in.coords,
+		Options:     url.Values(in.options),
+		Response:    out,
+		request:     in,
+	}
+}
+
+// chain wraps the invoker into interceptors, the first interceptor is the outermost one
+func chain(interceptors []Interceptor, invoke Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return invoke
+}
+
+// sync updates the request with the call fields modified by interceptors
+func (c *Call) sync() *request {
+	in := c.request
+	in.service, in.profile, in.coords = c.Service, c.Profile, c.Coordinates
+	in.options, in.header, in.observe = options(c.Options), c.Header, c.observe
+	return in
+}
+
+// observe records an http request of the call until the call is finished
+func (c *Call) observe(url string, statusCode int, stats CallStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.done {
+		stats.Attempts = c.Stats.Attempts + 1
+		c.URL, c.StatusCode, c.Stats = url, statusCode, stats
+	}
+}
+
+// begin starts recording of http requests of the call
+func (c *Call) begin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Start, c.done = time.Now(), false
+}
+
+// finish stops recording of late http requests (e.g. canceled hedged requests)
+func (c *Call) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Duration, c.done = time.Since(c.Start), true
+}
@@ -0,0 +1,124 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptorsSeeCall(t *testing.T) {
+	ts := httptest.NewServer(fixturedHTTPHandler("route_response_full", func(path, query string) {}))
+	defer ts.Close()
+
+	var order []string
+	var seen Call
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			order = append(order, "outer")
+			return invoke(ctx, call)
+		},
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			order = append(order, "inner")
+			err := invoke(ctx, call)
+			seen = Call{
+				Service:     call.Service,
+				Profile:     call.Profile,
+				Coordinates: call.Coordinates,
+				Options:     call.Options,
+				Response:    call.Response,
+				URL:         call.URL,
+				StatusCode:  call.StatusCode,
+				Start:       call.Start,
+				Duration:    call.Duration,
+			}
+			return err
+		},
+	}})
+
+	r, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: geometry, Steps: StepsTrue})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.Equal(t, "route", seen.Service)
+	assert.Equal(t, "car", seen.Profile)
+	assert.Equal(t, geometry, seen.Coordinates)
+	assert.Equal(t, "true", seen.Options.Get("steps"))
+	assert.Equal(t, ts.URL+"/route/v1/car/polyline(%7BaowFrerbM%7DPbI~Jyd@)?geometries=polyline6&steps=true", seen.URL)
+	assert.Equal(t, http.StatusOK, seen.StatusCode)
+	assert.False(t, seen.Start.IsZero())
+	assert.True(t, seen.Duration > 0)
+	assert.True(t, seen.Response == r)
+}
+
+func TestInterceptorModifiesCall(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/nearest/v1/foot/polyline({aowFrerbM}PbI~Jyd@)", r.URL.Path)
+		assert.Equal(t, "number=3", r.URL.RawQuery)
+		_, _ = w.Write([]byte(`{"code": "Ok", "waypoints": []}`))
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			call.Header = http.Header{"Authorization": {"Bearer token"}}
+			call.Profile = "foot"
+			call.Options.Set("number", "3")
+			return invoke(ctx, call)
+		},
+	}})
+
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+}
+
+func TestInterceptorShortCircuitsCall(t *testing.T) {
+	var calls int32
+	ts := nearestServer(&calls, http.StatusOK)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			if call.Profile == "fault" {
+				return errors.New("injected fault")
+			}
+			call.Response.(*NearestResponse).Waypoints = []NearestWaypoint{{Name: "cached"}}
+			return nil
+		},
+	}})
+
+	r, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	assert.Equal(t, "cached", r.Waypoints[0].Name)
+
+	_, err = osrm.Nearest(context.Background(), NearestRequest{Profile: "fault", Coordinates: geometry})
+	require.EqualError(t, err, "injected fault")
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestInterceptorSeesAPIErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code": "NoRoute", "message": "Impossible route between points"}`))
+	}))
+	defer ts.Close()
+
+	var seen error
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			seen = invoke(ctx, call)
+			return seen
+		},
+	}})
+
+	_, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: geometry})
+	require.EqualError(t, err, "NoRoute - Impossible route between points")
+	assert.Equal(t, err, seen)
+}
@@ -0,0 +1,413 @@
+package osrm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// float32Pow10 are exact float32 powers of ten used by the fast path of number parsing
+var float32Pow10 = [...]float32{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10}
+
+// jsonScanner is a minimal JSON scanner decoding large number arrays without reflection.
+// Values it does not decode itself are skipped and passed to encoding/json as raw bytes.
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+// object calls member for every member of the object positioned at its value,
+// member reports whether it decoded the value. Values of other members are skipped
+// and returned as a JSON object to be unmarshaled by encoding/json.
+func (s *jsonScanner) object(member func(key []byte) (bool, error)) ([]byte, error) {
+	if err := s.expect('{'); err != nil {
+		return nil, err
+	}
+	rest := []byte{'{'}
+	if s.consume('}') {
+		return append(rest, '}'), nil
+	}
+	for {
+		start := s.skipSpace()
+		key, err := s.string()
+		if err != nil {
+			return nil, err
+		}
+		keyEnd := s.pos
+		if err := s.expect(':'); err != nil {
+			return nil, err
+		}
+
+		ok, err := member(key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			raw, err := s.skip()
+			if err != nil {
+				return nil, err
+			}
+			if len(rest) > 1 {
+				rest = append(rest, ',')
+			}
+			rest = append(append(append(rest, s.data[start:keyEnd]...), ':'), raw...)
+		}
+
+		if s.consume('}') {
+			return append(rest, '}'), nil
+		}
+		if err := s.expect(','); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// float32s decodes an array of numbers with nulls replaced by the given value, null array is decoded as nil.
+// The slice is allocated once for all numbers of the array.
+func (s *jsonScanner) float32s(null float32) ([]float32, error) {
+	if s.null() {
+		return nil, nil
+	}
+	raw, err := s.skipNumbers()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float32, 0, bytes.Count(raw, []byte{','})+1)
+	err = (&jsonScanner{data: raw}).array(func(s *jsonScanner) error {
+		v, err := s.float32(null)
+		values = append(values, v)
+		return err
+	})
+	return values, err
+}
+
+// uint32s decodes an array of unsigned integers, null array is decoded as nil
+func (s *jsonScanner) uint32s() ([]uint32, error) {
+	if s.null() {
+		return nil, nil
+	}
+	raw, err := s.skipNumbers()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint32, 0, bytes.Count(raw, []byte{','})+1)
+	err = (&jsonScanner{data: raw}).array(func(s *jsonScanner) error {
+		v, err := s.uint(32)
+		values = append(values, uint32(v))
+		return err
+	})
+	return values, err
+}
+
+// uint64s decodes an array of unsigned integers, null array is decoded as nil
+func (s *jsonScanner) uint64s() ([]uint64, error) {
+	if s.null() {
+		return nil, nil
+	}
+	raw, err := s.skipNumbers()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint64, 0, bytes.Count(raw, []byte{','})+1)
+	err = (&jsonScanner{data: raw}).array(func(s *jsonScanner) error {
+		v, err := s.uint(64)
+		values = append(values, v)
+		return err
+	})
+	return values, err
+}
+
+// array calls element for every element of the array
+func (s *jsonScanner) array(element func(s *jsonScanner) error) error {
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	if s.consume(']') {
+		return nil
+	}
+	for {
+		if err := element(s); err != nil {
+			return err
+		}
+		if s.consume(']') {
+			return nil
+		}
+		if err := s.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+// float32 decodes a number, null is decoded as the given value
+func (s *jsonScanner) float32(null float32) (float32, error) {
+	if s.null() {
+		return null, nil
+	}
+	if v, ok := s.float32Fast(); ok {
+		return v, nil
+	}
+	num, err := s.number()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(string(num), 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q at offset %d", num, s.pos-len(num))
+	}
+	return float32(v), nil
+}
+
+// uint decodes an unsigned integer of the given bit size, null is decoded as zero
+func (s *jsonScanner) uint(bits int) (uint64, error) {
+	if s.null() {
+		return 0, nil
+	}
+	num, err := s.number()
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i, c := range num {
+		if c < '0' || c > '9' || i >= 19 {
+			// not a plain integer or too long to be summed without overflow checks
+			if v, err = strconv.ParseUint(string(num), 10, bits); err != nil {
+				return 0, fmt.Errorf("invalid unsigned integer %q at offset %d", num, s.pos-len(num))
+			}
+			return v, nil
+		}
+		v = v*10 + uint64(c-'0')
+	}
+	if bits < 64 && v > 1<<uint(bits)-1 {
+		return 0, fmt.Errorf("invalid unsigned integer %q at offset %d", num, s.pos-len(num))
+	}
+	return v, nil
+}
+
+// float32Fast parses a short decimal number without an exponent, which is the way OSRM
+// formats durations and distances, and reports whether it succeeded. The result is correctly
+// rounded since both the mantissa and the power of ten are exact float32 values divided once.
+// The scanner is not moved if the number could not be parsed.
+func (s *jsonScanner) float32Fast() (float32, bool) {
+	data, i := s.data, s.pos
+	negative := i < len(data) && data[i] == '-'
+	if negative {
+		i++
+	}
+
+	var mantissa uint32
+	digits, fraction, point := 0, 0, false
+	for ; i < len(data); i++ {
+		c := data[i]
+		if c >= '0' && c <= '9' {
+			mantissa = mantissa*10 + uint32(c-'0')
+			digits++
+			if point {
+				fraction++
+			}
+			if digits > 9 {
+				return 0, false
+			}
+			continue
+		}
+		if c == '.' && !point {
+			point = true
+			continue
+		}
+		if c == 'e' || c == 'E' || c == '.' || c == '+' || c == '-' {
+			return 0, false
+		}
+		break
+	}
+	if digits == 0 || mantissa > 1<<24 || fraction >= len(float32Pow10) {
+		return 0, false
+	}
+
+	s.pos = i
+	v := float32(mantissa) / float32Pow10[fraction]
+	if negative {
+		v = -v
+	}
+	return v, true
+}
+
+// number returns a number token
+func (s *jsonScanner) number() ([]byte, error) {
+	start := s.skipSpace()
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		s.pos++
+	}
+	if s.pos == start {
+		return nil, s.unexpected()
+	}
+	return s.data[start:s.pos], nil
+}
+
+// string returns raw contents of a string token, escape sequences are not decoded
+func (s *jsonScanner) string() ([]byte, error) {
+	if err := s.expect('"'); err != nil {
+		return nil, err
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			return s.data[start : s.pos-1], nil
+		}
+		s.pos++
+	}
+	return nil, errUnexpectedEndOfJSON
+}
+
+// skip skips a value and returns its raw bytes
+func (s *jsonScanner) skip() ([]byte, error) {
+	start := s.skipSpace()
+	if s.pos >= len(s.data) {
+		return nil, errUnexpectedEndOfJSON
+	}
+
+	switch s.data[s.pos] {
+	case '"':
+		if _, err := s.string(); err != nil {
+			return nil, err
+		}
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case '"':
+				if _, err := s.string(); err != nil {
+					return nil, err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.pos++
+			if depth == 0 {
+				return s.data[start:s.pos], nil
+			}
+		}
+		return nil, errUnexpectedEndOfJSON
+	default:
+		for s.pos < len(s.data) {
+			c := s.data[s.pos]
+			if c == ',' || c == '}' || c == ']' || isSpace(c) {
+				break
+			}
+			s.pos++
+		}
+	}
+	return s.data[start:s.pos], nil
+}
+
+// skipNumbers skips an array of numbers or an array of such arrays and returns its raw bytes.
+// Unlike skip it looks for ends of arrays with bytes.IndexByte, since they could not contain strings.
+func (s *jsonScanner) skipNumbers() ([]byte, error) {
+	start := s.skipSpace()
+	if err := s.expect('['); err != nil {
+		return nil, err
+	}
+	nested := s.consume('[')
+	for {
+		i := bytes.IndexByte(s.data[s.pos:], ']')
+		if i < 0 {
+			s.pos = len(s.data)
+			return nil, errUnexpectedEndOfJSON
+		}
+		s.pos += i + 1
+		if !nested || s.consume(']') {
+			return s.data[start:s.pos], nil
+		}
+	}
+}
+
+// null consumes null literal if it is the next token
+func (s *jsonScanner) null() bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == 'n' && bytes.HasPrefix(s.data[s.pos:], []byte("null")) {
+		s.pos += len("null")
+		return true
+	}
+	return false
+}
+
+// consume consumes the character if it is the next token
+func (s *jsonScanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *jsonScanner) expect(c byte) error {
+	if !s.consume(c) {
+		return s.unexpected()
+	}
+	return nil
+}
+
+// end checks that only spaces are left
+func (s *jsonScanner) end() error {
+	if s.skipSpace() < len(s.data) {
+		return s.unexpected()
+	}
+	return nil
+}
+
+func (s *jsonScanner) unexpected() error {
+	if s.pos >= len(s.data) {
+		return errUnexpectedEndOfJSON
+	}
+	return fmt.Errorf("invalid character %q at offset %d", s.data[s.pos], s.pos)
+}
+
+// skipSpace skips white spaces and returns the position of the next token
+func (s *jsonScanner) skipSpace() int {
+	for s.pos < len(s.data) && s.data[s.pos] <= ' ' && isSpace(s.data[s.pos]) {
+		s.pos++
+	}
+	return s.pos
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// errUnexpectedEndOfJSON matches the error of encoding/json for truncated input
+var errUnexpectedEndOfJSON = errors.New("unexpected end of JSON input")
+
+// unmarshalObject decodes members of an object with the scanner,
+// the rest of members is unmarshaled into the given value with encoding/json
+func unmarshalObject(data []byte, member func(key []byte, s *jsonScanner) (bool, error), rest interface{}) error {
+	s := &jsonScanner{data: data}
+	if s.null() {
+		return s.end()
+	}
+	members, err := s.object(func(key []byte) (bool, error) {
+		return member(key, s)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.end(); err != nil {
+		return err
+	}
+	if len(members) > len("{}") {
+		return json.Unmarshal(members, rest)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package osrm
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONScannerFloat32Fast(t *testing.T) {
+	for _, s := range []string{"0", "-0", "12", "12.5", "-7.25", "0.1", "1234567", "16777216", "0.0000001"} {
+		expected, err := strconv.ParseFloat(s, 32)
+		require.NoError(t, err, s)
+		v, ok := (&jsonScanner{data: []byte(s)}).float32Fast()
+		if assert.True(t, ok, s) {
+			assert.Equal(t, float32(expected), v, s)
+		}
+	}
+
+	for _, s := range []string{"", "-", "16777217", "123456789.1", "1e5", "1.2.3", "0.00000000001", "abc"} {
+		scanner := &jsonScanner{data: []byte(s)}
+		_, ok := scanner.float32Fast()
+		assert.False(t, ok, s)
+		assert.Equal(t, 0, scanner.pos, s)
+	}
+
+	// the fast path is correctly rounded for every number it accepts
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		s := strconv.FormatFloat(r.Float64()*float64(r.Intn(100000)), 'f', r.Intn(5), 64)
+		expected, err := strconv.ParseFloat(s, 32)
+		require.NoError(t, err)
+		if v, ok := (&jsonScanner{data: []byte(s)}).float32Fast(); ok {
+			require.Equal(t, float32(expected), v, s)
+		}
+	}
+}
+
+func TestJSONScannerNumberArrays(t *testing.T) {
+	s := &jsonScanner{data: []byte(` [1, 2.5 ,null,-3e2, 123456789.25] `)}
+	floats, err := s.float32s(-1)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 2.5, -1, -300, 123456789.25}, floats)
+	assert.NoError(t, s.end())
+
+	s = &jsonScanner{data: []byte(`null`)}
+	floats, err = s.float32s(0)
+	require.NoError(t, err)
+	assert.Nil(t, floats)
+
+	s = &jsonScanner{data: []byte(`[0, 4294967295, null]`)}
+	uints, err := s.uint32s()
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{0, 4294967295, 0}, uints)
+
+	s = &jsonScanner{data: []byte(`[4294967296]`)}
+	_, err = s.uint32s()
+	assert.EqualError(t, err, `invalid unsigned integer "4294967296" at offset 1`)
+
+	s = &jsonScanner{data: []byte(`[18446744073709551615, 7]`)}
+	nodes, err := s.uint64s()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{18446744073709551615, 7}, nodes)
+
+	for data, expected := range map[string]string{
+		`[1,2`:    "unexpected end of JSON input",
+		`[1;2]`:   `invalid character ';' at offset 2`,
+		`[1,"a"]`: `invalid character '"' at offset 3`,
+		`[1e]`:    `invalid number "1e" at offset 1`,
+		`{}`:      `invalid character '{' at offset 0`,
+	} {
+		_, err := (&jsonScanner{data: []byte(data)}).float32s(0)
+		assert.EqualError(t, err, expected, data)
+	}
+}
+
+func TestUnmarshalObject(t *testing.T) {
+	var rest struct {
+		Name  string              `json:"name"`
+		Other map[string][]string `json:"other"`
+	}
+	var values []float32
+	err := unmarshalObject([]byte(`{"name": "a\"]}", "values": [1, 2], "other": {"x": ["[", "}"]}}`), func(key []byte, s *jsonScanner) (bool, error) {
+		if string(key) != "values" {
+			return false, nil
+		}
+		var err error
+		values, err = s.float32s(0)
+		return true, err
+	}, &rest)
+	require.NoError(t, err)
+
+	assert.Equal(t, []float32{1, 2}, values)
+	assert.Equal(t, `a"]}`, rest.Name)
+	assert.Equal(t, map[string][]string{"x": {"[", "}"}}, rest.Other)
+
+	ignore := func(key []byte, s *jsonScanner) (bool, error) { return false, nil }
+	assert.NoError(t, unmarshalObject([]byte(` null `), ignore, &rest))
+	assert.NoError(t, unmarshalObject([]byte(`{}`), ignore, &rest))
+	assert.EqualError(t, unmarshalObject([]byte(`{"name": "a"} x`), ignore, &rest), `invalid character 'x' at offset 14`)
+	assert.EqualError(t, unmarshalObject([]byte(`{"name" "a"}`), ignore, &rest), `invalid character '"' at offset 8`)
+	assert.EqualError(t, unmarshalObject([]byte(`{"name": [1, 2}`), ignore, &rest), "unexpected end of JSON input")
+}
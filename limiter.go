@@ -0,0 +1,146 @@
+package osrm
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority names a lane of a limiter, requests of a lane share its concurrency limit
+type Priority string
+
+// Common priorities
+const (
+	PriorityInteractive Priority = "interactive"
+	PriorityBatch       Priority = "batch"
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context sending requests through the lane of the priority
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// LimiterConfig represents configuration options of a limiter
+type LimiterConfig struct {
+	// MaxInFlight maps priorities to maximum numbers of requests in progress of their lanes.
+	// Requests of priorities missing in the map are sent through the lane of the default priority,
+	// which is not limited if it is missing as well.
+	MaxInFlight map[Priority]int
+	// DefaultPriority is a priority of requests without a priority in their context,
+	// interactive priority is used if not set
+	DefaultPriority Priority
+}
+
+// LaneStats represents a state of a lane
+type LaneStats struct {
+	// MaxInFlight is a limit of the lane, zero means no limit
+	MaxInFlight int
+	// InFlight is a number of requests in progress
+	InFlight int
+	// Queued is a number of requests waiting for their turn
+	Queued int
+}
+
+// Limiter limits numbers of concurrent requests of every lane, so batch jobs could not
+// take all OSRM threads from interactive requests. Requests over the limit wait for their
+// turn until the context is done. Every HTTP request takes a turn of its own, so retries and
+// hedged duplicates are limited too, while backoff between retries doesn't hold a turn.
+// A limiter could be shared between several clients.
+type Limiter struct {
+	defaultPriority Priority
+
+	mu    sync.Mutex
+	lanes map[Priority]*lane
+}
+
+// lane is a semaphore of a lane
+type lane struct {
+	max      int
+	slots    chan struct{}
+	inFlight int
+	queued   int
+}
+
+// NewLimiter creates a limiter
+func NewLimiter(cfg LimiterConfig) *Limiter {
+	if cfg.DefaultPriority == "" {
+		cfg.DefaultPriority = PriorityInteractive
+	}
+
+	l := &Limiter{defaultPriority: cfg.DefaultPriority, lanes: map[Priority]*lane{}}
+	for p, max := range cfg.MaxInFlight {
+		l.lanes[p] = newLane(max)
+	}
+	if _, ok := l.lanes[l.defaultPriority]; !ok {
+		l.lanes[l.defaultPriority] = newLane(0)
+	}
+	return l
+}
+
+func newLane(max int) *lane {
+	l := &lane{max: max}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Stats returns states of the configured lanes and the default one
+func (l *Limiter) Stats() map[Priority]LaneStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[Priority]LaneStats, len(l.lanes))
+	for p, ln := range l.lanes {
+		stats[p] = LaneStats{MaxInFlight: ln.max, InFlight: ln.inFlight, Queued: ln.queued}
+	}
+	return stats
+}
+
+// acquire waits for a turn in the lane of the context priority, the returned func releases it.
+// Unknown priorities share the default lane, so arbitrary priorities could not grow the lanes.
+func (l *Limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		p = l.defaultPriority
+	}
+
+	ln, ok := l.lanes[p]
+	if !ok {
+		ln = l.lanes[l.defaultPriority]
+	}
+
+	l.mu.Lock()
+	ln.queued++
+	l.mu.Unlock()
+
+	if ln.slots != nil {
+		select {
+		case ln.slots <- struct{}{}:
+		case <-ctx.Done():
+			l.mu.Lock()
+			ln.queued--
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+
+	l.mu.Lock()
+	ln.queued--
+	ln.inFlight++
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		ln.inFlight--
+		l.mu.Unlock()
+		if ln.slots != nil {
+			<-ln.slots
+		}
+	}, nil
+}
@@ -0,0 +1,160 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFor waits up to a second for the condition
+func waitFor(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+	}
+}
+
+func TestLimiterLanes(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxInFlight: map[Priority]int{PriorityBatch: 1}})
+	batch := WithPriority(context.Background(), PriorityBatch)
+
+	release, err := l.acquire(batch)
+	require.NoError(t, err)
+
+	// the interactive lane is not limited by the batch one
+	releaseInteractive, err := l.acquire(context.Background())
+	require.NoError(t, err)
+
+	acquired := make(chan func())
+	go func() {
+		r, err := l.acquire(batch)
+		assert.NoError(t, err)
+		acquired <- r
+	}()
+
+	waitFor(t, func() bool { return l.Stats()[PriorityBatch].Queued == 1 })
+	assert.Equal(t, map[Priority]LaneStats{
+		PriorityBatch:       {MaxInFlight: 1, InFlight: 1, Queued: 1},
+		PriorityInteractive: {InFlight: 1},
+	}, l.Stats())
+
+	release()
+	releaseQueued := <-acquired
+	releaseQueued()
+	releaseInteractive()
+
+	assert.Equal(t, map[Priority]LaneStats{
+		PriorityBatch:       {MaxInFlight: 1},
+		PriorityInteractive: {},
+	}, l.Stats())
+}
+
+func TestLimiterContextCancel(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxInFlight: map[Priority]int{PriorityInteractive: 1}})
+
+	release, err := l.acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, LaneStats{MaxInFlight: 1, InFlight: 1}, l.Stats()[PriorityInteractive])
+}
+
+func TestLimiterUnknownPriority(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxInFlight: map[Priority]int{PriorityInteractive: 1, PriorityBatch: 2}})
+
+	release, err := l.acquire(WithPriority(context.Background(), "unknown"))
+	require.NoError(t, err)
+	defer release()
+
+	// unknown priorities share the default lane instead of getting lanes of their own
+	assert.Equal(t, map[Priority]LaneStats{
+		PriorityBatch:       {MaxInFlight: 2},
+		PriorityInteractive: {MaxInFlight: 1, InFlight: 1},
+	}, l.Stats())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLimiterReleasesTurnDuringBackoff(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+
+	limiter := NewLimiter(LimiterConfig{MaxInFlight: map[Priority]int{PriorityInteractive: 1}})
+	retried := NewWithConfig(Config{ServerURL: ts.URL, Limiter: limiter, RetryPolicy: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, Jitter: -1}})
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Limiter: limiter})
+
+	errs := make(chan error)
+	go func() {
+		_, err := retried.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		errs <- err
+	}()
+
+	// the retried call waits for its backoff without holding the only turn
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&calls) == 1 && limiter.Stats()[PriorityInteractive].InFlight == 0
+	})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	select {
+	case err := <-errs:
+		t.Fatalf("the retried call is done before its backoff: %v", err)
+	default:
+	}
+
+	require.NoError(t, <-errs)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLimiterLimitsRequests(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+
+	limiter := NewLimiter(LimiterConfig{MaxInFlight: map[Priority]int{PriorityBatch: 1}})
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Limiter: limiter})
+	ctx := WithPriority(context.Background(), PriorityBatch)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := osrm.Nearest(ctx, NearestRequest{Profile: "car", Coordinates: geometry})
+			errs <- err
+		}()
+	}
+
+	<-started
+	waitFor(t, func() bool { return limiter.Stats()[PriorityBatch].Queued == 1 })
+	unblock <- struct{}{}
+	<-started
+	unblock <- struct{}{}
+
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+}
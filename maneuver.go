@@ -0,0 +1,132 @@
+package osrm
+
+// ManeuverType represents a type of maneuver of a route step
+type ManeuverType string
+
+// Supported maneuver types.
+// See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md#stepmaneuver-object for details.
+const (
+	ManeuverTurn           ManeuverType = "turn"
+	ManeuverNewName        ManeuverType = "new name"
+	ManeuverDepart         ManeuverType = "depart"
+	ManeuverArrive         ManeuverType = "arrive"
+	ManeuverMerge          ManeuverType = "merge"
+	ManeuverRamp           ManeuverType = "ramp" // deprecated by OSRM, replaced by on ramp and off ramp
+	ManeuverOnRamp         ManeuverType = "on ramp"
+	ManeuverOffRamp        ManeuverType = "off ramp"
+	ManeuverFork           ManeuverType = "fork"
+	ManeuverEndOfRoad      ManeuverType = "end of road"
+	ManeuverUseLane        ManeuverType = "use lane" // deprecated by OSRM, lanes are reported by intersections
+	ManeuverContinue       ManeuverType = "continue"
+	ManeuverRoundabout     ManeuverType = "roundabout"
+	ManeuverRotary         ManeuverType = "rotary"
+	ManeuverRoundaboutTurn ManeuverType = "roundabout turn"
+	ManeuverNotification   ManeuverType = "notification"
+	ManeuverExitRoundabout ManeuverType = "exit roundabout"
+	ManeuverExitRotary     ManeuverType = "exit rotary"
+)
+
+var maneuverTypes = map[ManeuverType]bool{
+	ManeuverTurn:           true,
+	ManeuverNewName:        true,
+	ManeuverDepart:         true,
+	ManeuverArrive:         true,
+	ManeuverMerge:          true,
+	ManeuverRamp:           true,
+	ManeuverOnRamp:         true,
+	ManeuverOffRamp:        true,
+	ManeuverFork:           true,
+	ManeuverEndOfRoad:      true,
+	ManeuverUseLane:        true,
+	ManeuverContinue:       true,
+	ManeuverRoundabout:     true,
+	ManeuverRotary:         true,
+	ManeuverRoundaboutTurn: true,
+	ManeuverNotification:   true,
+	ManeuverExitRoundabout: true,
+	ManeuverExitRotary:     true,
+}
+
+// IsValid reports whether the maneuver type is known
+func (t ManeuverType) IsValid() bool {
+	return maneuverTypes[t]
+}
+
+// String returns ManeuverType as a string
+func (t ManeuverType) String() string {
+	return string(t)
+}
+
+// ManeuverModifier represents a direction change of a maneuver
+type ManeuverModifier string
+
+// Supported maneuver modifiers
+const (
+	ModifierUTurn       ManeuverModifier = "uturn"
+	ModifierSharpRight  ManeuverModifier = "sharp right"
+	ModifierRight       ManeuverModifier = "right"
+	ModifierSlightRight ManeuverModifier = "slight right"
+	ModifierStraight    ManeuverModifier = "straight"
+	ModifierSlightLeft  ManeuverModifier = "slight left"
+	ModifierLeft        ManeuverModifier = "left"
+	ModifierSharpLeft   ManeuverModifier = "sharp left"
+)
+
+var maneuverModifiers = map[ManeuverModifier]bool{
+	ModifierUTurn:       true,
+	ModifierSharpRight:  true,
+	ModifierRight:       true,
+	ModifierSlightRight: true,
+	ModifierStraight:    true,
+	ModifierSlightLeft:  true,
+	ModifierLeft:        true,
+	ModifierSharpLeft:   true,
+}
+
+// IsValid reports whether the maneuver modifier is known
+func (m ManeuverModifier) IsValid() bool {
+	return maneuverModifiers[m]
+}
+
+// String returns ManeuverModifier as a string
+func (m ManeuverModifier) String() string {
+	return string(m)
+}
+
+// LaneIndication represents a turn marked on a lane
+type LaneIndication string
+
+// Supported lane indications
+const (
+	LaneNone        LaneIndication = "none"
+	LaneUTurn       LaneIndication = "uturn"
+	LaneSharpRight  LaneIndication = "sharp right"
+	LaneRight       LaneIndication = "right"
+	LaneSlightRight LaneIndication = "slight right"
+	LaneStraight    LaneIndication = "straight"
+	LaneSlightLeft  LaneIndication = "slight left"
+	LaneLeft        LaneIndication = "left"
+	LaneSharpLeft   LaneIndication = "sharp left"
+)
+
+var laneIndications = map[LaneIndication]bool{
+	LaneNone:        true,
+	LaneUTurn:       true,
+	LaneSharpRight:  true,
+	LaneRight:       true,
+	LaneSlightRight: true,
+	LaneStraight:    true,
+	LaneSlightLeft:  true,
+	LaneLeft:        true,
+	LaneSharpLeft:   true,
+}
+
+// IsValid reports whether the lane indication is known
+func (l LaneIndication) IsValid() bool {
+	return laneIndications[l]
+}
+
+// String returns LaneIndication as a string
+func (l LaneIndication) String() string {
+	return string(l)
+}
@@ -0,0 +1,86 @@
+package osrm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManeuverTypeIsValid(t *testing.T) {
+	assert.True(t, ManeuverDepart.IsValid())
+	assert.True(t, ManeuverExitRotary.IsValid())
+	assert.False(t, ManeuverType("").IsValid())
+	assert.False(t, ManeuverType("teleport").IsValid())
+}
+
+func TestManeuverModifierIsValid(t *testing.T) {
+	assert.True(t, ModifierUTurn.IsValid())
+	assert.True(t, ModifierSharpLeft.IsValid())
+	assert.False(t, ManeuverModifier("").IsValid())
+	assert.False(t, ManeuverModifier("none").IsValid())
+}
+
+func TestLaneIndicationIsValid(t *testing.T) {
+	assert.True(t, LaneNone.IsValid())
+	assert.True(t, LaneSlightRight.IsValid())
+	assert.False(t, LaneIndication("").IsValid())
+	assert.False(t, LaneIndication("backwards").IsValid())
+}
+
+func TestUnmarshalRouteStep(t *testing.T) {
+	in := []byte(`{
+		"intersections": [{
+			"out": 1,
+			"in": 0,
+			"entry": [false, true],
+			"bearings": [10, 92],
+			"classes": ["toll", "motorway"],
+			"location": [13.388798, 52.517033],
+			"lanes": [
+				{"indications": ["left", "straight"], "valid": false},
+				{"indications": ["right"], "valid": true}
+			]
+		}],
+		"geometry": "w{_tlAnb_clCfEz@zJf@",
+		"maneuver": {
+			"bearing_after": 202,
+			"location": [13.388798, 52.517033],
+			"bearing_before": 299,
+			"type": "roundabout",
+			"modifier": "slight right",
+			"exit": 2
+		},
+		"name": "Lortzingstraße",
+		"ref": "B 96",
+		"pronunciation": "ˈlɔʁtsɪŋˌʃtʁaːsə",
+		"destinations": "Berlin-Mitte",
+		"exits": "3;4",
+		"rotary_name": "Großer Stern",
+		"rotary_pronunciation": "ˈɡʁoːsɐ ʃtɛʁn",
+		"mode": "driving",
+		"duration": 15.6,
+		"distance": 152.3
+	}`)
+
+	var step RouteStep
+	require.NoError(t, json.Unmarshal(in, &step))
+
+	assert.Equal(t, "B 96", step.Ref)
+	assert.Equal(t, "ˈlɔʁtsɪŋˌʃtʁaːsə", step.Pronunciation)
+	assert.Equal(t, "Berlin-Mitte", step.Destinations)
+	assert.Equal(t, "3;4", step.Exits)
+	assert.Equal(t, "Großer Stern", step.RotaryName)
+	assert.Equal(t, "ˈɡʁoːsɐ ʃtɛʁn", step.RotaryPronunciation)
+
+	assert.Equal(t, ManeuverRoundabout, step.Maneuver.Type)
+	assert.Equal(t, ModifierSlightRight, step.Maneuver.Modifier)
+
+	require.Len(t, step.Intersections, 1)
+	intersection := step.Intersections[0]
+	assert.Equal(t, []string{"toll", "motorway"}, intersection.Classes)
+	require.Len(t, intersection.Lanes, 2)
+	assert.Equal(t, []LaneIndication{LaneLeft, LaneStraight}, intersection.Lanes[0].Indications)
+	assert.Equal(t, []LaneIndication{LaneRight}, intersection.Lanes[1].Indications)
+}
@@ -4,18 +4,26 @@ import geo "github.com/paulmach/go.geo"
 
 // MatchRequest represents a request to the match method
 type MatchRequest struct {
+	GeneralOptions
 	Profile     string
 	Coordinates Geometry
+	// Bearings, Radiuses and Hints are kept for compatibility, their counterparts
+	// in GeneralOptions take precedence if they are set.
+	//
+	// Deprecated: use GeneralOptions.Bearings, GeneralOptions.Radiuses (see NewRadiuses) and GeneralOptions.Hints.
 	Bearings    []Bearing
+	Radiuses    []float64
+	Hints       []string
 	Steps       Steps
 	Annotations Annotations
 	Tidy        Tidy
 	Timestamps  []int64
-	Radiuses    []float64
-	Hints       []string
 	Overview    Overview
 	Gaps        Gaps
 	Geometries  Geometries
+	// Waypoints lists indices of input coordinates to be treated as leg boundaries,
+	// all coordinates are boundaries if not set. The first and the last coordinates should be included.
+	Waypoints []int
 }
 
 // MatchResponse represents a response from the match method
@@ -23,6 +31,22 @@ type MatchResponse struct {
 	ResponseStatus
 	Matchings   []Matching    `json:"matchings"`
 	Tracepoints []*Tracepoint `json:"tracepoints"`
+
+	// waypoints are copied from the request to map tracepoints to legs
+	waypoints []int
+}
+
+// MatchedPoint represents a result of matching of an input coordinate
+type MatchedPoint struct {
+	// Dropped reports whether the coordinate was considered an outlier and was not matched.
+	// Matching and leg indices of a dropped coordinate are -1.
+	Dropped       bool
+	Location      geo.Point
+	MatchingIndex int
+	// LegIndex is an index of the matching leg starting at the coordinate
+	// or containing it if the coordinate is not a waypoint. The last waypoint of a matching ends its last leg.
+	LegIndex   int
+	Tracepoint *Tracepoint
 }
 
 // Matching represents an array of Route objects that assemble the trace
@@ -41,22 +65,58 @@ func (r MatchRequest) request() *request {
 	if len(r.Timestamps) > 0 {
 		options.addInt64("timestamps", r.Timestamps...)
 	}
-	if len(r.Radiuses) > 0 {
-		options.addFloat("radiuses", r.Radiuses...)
-	}
-	if len(r.Hints) > 0 {
-		options.add("hints", r.Hints...)
+	if len(r.Waypoints) > 0 {
+		options.addInt("waypoints", r.Waypoints...)
 	}
-	if len(r.Bearings) > 0 {
-		options.set("bearings", bearings(r.Bearings))
+	if len(r.GeneralOptions.Radiuses) == 0 && len(r.Radiuses) > 0 {
+		options.addFloat("radiuses", r.Radiuses...)
 	}
 
 	return &request{
 		profile: r.Profile,
 		coords:  r.Coordinates,
 		service: "match",
-		options: options,
+		options: r.GeneralOptions.withBearings(r.Bearings).withHints(r.Hints).apply(options),
+	}
+}
+
+// MatchedPoints returns matching results for each input coordinate in the order of the request
+func (r MatchResponse) MatchedPoints() []MatchedPoint {
+	waypoints := make(map[int]bool, len(r.waypoints))
+	for _, w := range r.waypoints {
+		waypoints[w] = true
+	}
+
+	// number of leg boundaries passed so far in every matching
+	passed := make(map[int]int, len(r.Matchings))
+	points := make([]MatchedPoint, len(r.Tracepoints))
+	for i, tp := range r.Tracepoints {
+		if tp == nil {
+			points[i] = MatchedPoint{Dropped: true, MatchingIndex: -1, LegIndex: -1}
+			continue
+		}
+
+		if len(waypoints) == 0 || waypoints[i] {
+			passed[tp.MatchingIndex]++
+		}
+		leg := passed[tp.MatchingIndex] - 1
+		if tp.MatchingIndex < len(r.Matchings) {
+			if legs := len(r.Matchings[tp.MatchingIndex].Legs); leg >= legs {
+				leg = legs - 1
+			}
+		}
+		if leg < 0 {
+			leg = 0
+		}
+
+		points[i] = MatchedPoint{
+			Location:      tp.Location,
+			MatchingIndex: tp.MatchingIndex,
+			LegIndex:      leg,
+			Tracepoint:    tp,
+		}
 	}
+	return points
 }
 
 // Tracepoint represents a matched point on a route
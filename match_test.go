@@ -3,6 +3,7 @@ package osrm
 import (
 	"testing"
 
+	geo "github.com/paulmach/go.geo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,6 +25,38 @@ func TestEmptyMatchRequestOptions(t *testing.T) {
 			},
 			expectedURI: "geometries=polyline6&radiuses=0.123123;0.12312&timestamps=0;1;2",
 		},
+		{
+			name: "with timestamps and general radiuses",
+			request: MatchRequest{
+				Timestamps: []int64{0, 1, 2},
+				GeneralOptions: GeneralOptions{
+					Radiuses: []Radius{0.123123, 0.12312},
+				},
+			},
+			expectedURI: "geometries=polyline6&radiuses=0.123123;0.12312&timestamps=0;1;2",
+		},
+		{
+			name: "with deprecated radiuses, hints and bearings",
+			request: MatchRequest{
+				Timestamps: []int64{0, 1},
+				Radiuses:   []float64{0.123123, 0},
+				Hints:      []string{"a", "b"},
+				Bearings:   []Bearing{{0, 20}, {10, 20}},
+			},
+			expectedURI: "bearings=0%2C20%3B10%2C20&geometries=polyline6&hints=a;b&radiuses=0.123123;0&timestamps=0;1",
+		},
+		{
+			name: "with general options taking precedence over deprecated ones",
+			request: MatchRequest{
+				Radiuses: []float64{1, 2},
+				Hints:    []string{"a", "b"},
+				GeneralOptions: GeneralOptions{
+					Radiuses: NewRadiuses(3, 4),
+					Hints:    []string{"c", "d"},
+				},
+			},
+			expectedURI: "geometries=polyline6&hints=c;d&radiuses=3;4",
+		},
 		{
 			name: "with gaps and tidy",
 			request: MatchRequest{
@@ -41,6 +74,15 @@ func TestEmptyMatchRequestOptions(t *testing.T) {
 			},
 			expectedURI: "geometries=polyline6&hints=a;b;c;d",
 		},
+		{
+			name: "with general hints",
+			request: MatchRequest{
+				GeneralOptions: GeneralOptions{
+					Hints: []string{"a", "b", "c", "d"},
+				},
+			},
+			expectedURI: "geometries=polyline6&hints=a;b;c;d",
+		},
 		{
 			name: "with bearings",
 			request: MatchRequest{
@@ -50,6 +92,27 @@ func TestEmptyMatchRequestOptions(t *testing.T) {
 			},
 			expectedURI: "bearings=0%2C20%3B10%2C20&geometries=polyline6",
 		},
+		{
+			name: "with general bearings",
+			request: MatchRequest{
+				GeneralOptions: GeneralOptions{
+					Bearings: []Bearing{
+						{0, 20}, {10, 20},
+					},
+				},
+			},
+			expectedURI: "bearings=0%2C20%3B10%2C20&geometries=polyline6",
+		},
+		{
+			name: "with waypoints and approaches",
+			request: MatchRequest{
+				Waypoints: []int{0, 2},
+				GeneralOptions: GeneralOptions{
+					Approaches: []Approach{ApproachCurb, ApproachDefault, ApproachCurb},
+				},
+			},
+			expectedURI: "approaches=curb;;curb&geometries=polyline6&waypoints=0;2",
+		},
 		{
 			name: "custom overview option",
 			request: MatchRequest{
@@ -68,3 +131,66 @@ func TestEmptyMatchRequestOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchedPoints(t *testing.T) {
+	resp := MatchResponse{
+		Matchings: []Matching{
+			{Route: Route{Legs: make([]RouteLeg, 2)}},
+			{Route: Route{Legs: make([]RouteLeg, 1)}},
+		},
+		Tracepoints: []*Tracepoint{
+			{Index: 0, MatchingIndex: 0, Location: geo.Point{1, 1}},
+			nil,
+			{Index: 1, MatchingIndex: 0, Location: geo.Point{3, 3}},
+			{Index: 2, MatchingIndex: 0, Location: geo.Point{4, 4}},
+			{Index: 0, MatchingIndex: 1, Location: geo.Point{5, 5}},
+			{Index: 1, MatchingIndex: 1, Location: geo.Point{6, 6}},
+		},
+	}
+
+	points := resp.MatchedPoints()
+	assert.Len(t, points, 6)
+
+	expected := []struct {
+		dropped         bool
+		matching, leg   int
+		location        geo.Point
+		tracepointIndex int
+	}{
+		{false, 0, 0, geo.Point{1, 1}, 0},
+		{true, -1, -1, geo.Point{}, -1},
+		{false, 0, 1, geo.Point{3, 3}, 2},
+		{false, 0, 1, geo.Point{4, 4}, 3},
+		{false, 1, 0, geo.Point{5, 5}, 4},
+		{false, 1, 0, geo.Point{6, 6}, 5},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.dropped, points[i].Dropped, "point %d", i)
+		assert.Equal(t, e.matching, points[i].MatchingIndex, "point %d", i)
+		assert.Equal(t, e.leg, points[i].LegIndex, "point %d", i)
+		assert.Equal(t, e.location, points[i].Location, "point %d", i)
+		if e.tracepointIndex >= 0 {
+			assert.Equal(t, resp.Tracepoints[e.tracepointIndex], points[i].Tracepoint, "point %d", i)
+		} else {
+			assert.Nil(t, points[i].Tracepoint, "point %d", i)
+		}
+	}
+}
+
+func TestMatchedPointsWithWaypoints(t *testing.T) {
+	resp := MatchResponse{
+		Matchings: []Matching{
+			{Route: Route{Legs: make([]RouteLeg, 2)}},
+		},
+		Tracepoints: []*Tracepoint{
+			{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}, {Index: 4},
+		},
+		waypoints: []int{0, 2, 4},
+	}
+
+	var legs []int
+	for _, p := range resp.MatchedPoints() {
+		legs = append(legs, p.LegIndex)
+	}
+	assert.Equal(t, []int{0, 0, 1, 1, 1}, legs)
+}
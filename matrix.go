@@ -0,0 +1,276 @@
+package osrm
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Matrix represents durations or distances of the table method between sources and destinations.
+// Unlike plain slices it tells pairs without a route apart from zero values.
+//
+// Cells are addressed by indices of the request coordinates: rows and columns are mapped
+// through TableRequest.Sources and TableRequest.Destinations, all coordinates are used if they are not set.
+type Matrix struct {
+	rows, cols int
+	// values are stored in row-major order, NaN stands for an unreachable pair
+	values []float32
+	// sources and destinations map rows and columns to coordinate indices, nil means identity
+	sources, destinations []int
+}
+
+// MatrixCell represents a single value of a matrix
+type MatrixCell struct {
+	Source, Destination int
+	Value               float32
+	// Reachable is false if OSRM could not find a route between the pair
+	Reachable bool
+}
+
+// newMatrix creates a matrix filled with unreachable cells
+func newMatrix(rows, cols int) Matrix {
+	values := make([]float32, rows*cols)
+	for i := range values {
+		values[i] = float32(math.NaN())
+	}
+	return Matrix{rows: rows, cols: cols, values: values}
+}
+
+// withIndices returns the matrix mapped to coordinate indices
+func (m Matrix) withIndices(sources, destinations []int) Matrix {
+	if len(sources) == m.rows {
+		m.sources = sources
+	}
+	if len(destinations) == m.cols {
+		m.destinations = destinations
+	}
+	return m
+}
+
+// Rows returns the number of sources
+func (m Matrix) Rows() int {
+	return m.rows
+}
+
+// Cols returns the number of destinations
+func (m Matrix) Cols() int {
+	return m.cols
+}
+
+// At returns a value between the source and the destination coordinates
+// and reports whether the destination is reachable from the source.
+func (m Matrix) At(source, destination int) (float32, bool) {
+	row, ok := m.row(source)
+	if !ok {
+		return 0, false
+	}
+	col, ok := m.col(destination)
+	if !ok {
+		return 0, false
+	}
+	return m.cell(row, col)
+}
+
+// Reachable reports whether there is a route between the source and the destination coordinates
+func (m Matrix) Reachable(source, destination int) bool {
+	_, ok := m.At(source, destination)
+	return ok
+}
+
+// Row returns cells from the source coordinate to every destination in the order of destinations
+func (m Matrix) Row(source int) []MatrixCell {
+	row, ok := m.row(source)
+	if !ok {
+		return nil
+	}
+	cells := make([]MatrixCell, m.cols)
+	for col := range cells {
+		cells[col] = m.matrixCell(row, col)
+	}
+	return cells
+}
+
+// Column returns cells from every source to the destination coordinate in the order of sources
+func (m Matrix) Column(destination int) []MatrixCell {
+	col, ok := m.col(destination)
+	if !ok {
+		return nil
+	}
+	cells := make([]MatrixCell, m.rows)
+	for row := range cells {
+		cells[row] = m.matrixCell(row, col)
+	}
+	return cells
+}
+
+// ArgMinRow returns the destination coordinate with the least value from the source coordinate.
+// It reports false if no destination is reachable.
+func (m Matrix) ArgMinRow(source int) (int, bool) {
+	return argMin(m.Row(source), func(c MatrixCell) int { return c.Destination })
+}
+
+// ArgMinColumn returns the source coordinate with the least value to the destination coordinate.
+// It reports false if the destination is not reachable from any source.
+func (m Matrix) ArgMinColumn(destination int) (int, bool) {
+	return argMin(m.Column(destination), func(c MatrixCell) int { return c.Source })
+}
+
+// Float32s returns the matrix as slices of rows with the given value put in place of unreachable pairs.
+// It eases migration from the plain [][]float32 representation.
+func (m Matrix) Float32s(unreachable float32) [][]float32 {
+	if m.values == nil {
+		return nil
+	}
+	s := make([][]float32, m.rows)
+	for row := range s {
+		s[row] = make([]float32, m.cols)
+		for col := range s[row] {
+			if v, ok := m.cell(row, col); ok {
+				s[row][col] = v
+			} else {
+				s[row][col] = unreachable
+			}
+		}
+	}
+	return s
+}
+
+// UnmarshalJSON decodes a matrix from an array of rows with null values for unreachable pairs.
+// Values are parsed straight into the matrix without reflection, since tables could have millions of cells.
+func (m *Matrix) UnmarshalJSON(b []byte) error {
+	s := &jsonScanner{data: b}
+	if err := m.decode(s); err != nil {
+		return err
+	}
+	return s.end()
+}
+
+// decode decodes a matrix positioned at the scanner
+func (m *Matrix) decode(s *jsonScanner) error {
+	*m = Matrix{}
+	if s.null() {
+		return nil
+	}
+	raw, err := s.skipNumbers()
+	if err != nil {
+		return err
+	}
+
+	// every row but the last one and every cell but the last one in a row are followed by commas
+	values := make([]float32, 0, bytes.Count(raw, []byte{','})+1)
+	rows, cols := 0, 0
+	err = (&jsonScanner{data: raw}).array(func(s *jsonScanner) error {
+		n := len(values)
+		err := s.array(func(s *jsonScanner) error {
+			v, err := s.float32(float32(math.NaN()))
+			values = append(values, v)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			cols = len(values)
+		} else if len(values)-n != cols {
+			return fmt.Errorf("matrix row %d has %d columns, %d expected", rows, len(values)-n, cols)
+		}
+		rows++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*m = Matrix{rows: rows, cols: cols, values: values}
+	return nil
+}
+
+// MarshalJSON encodes a matrix as an array of rows with null values for unreachable pairs
+func (m Matrix) MarshalJSON() ([]byte, error) {
+	if m.values == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for row := 0; row < m.rows; row++ {
+		if row > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for col := 0; col < m.cols; col++ {
+			if col > 0 {
+				buf.WriteByte(',')
+			}
+			if v, ok := m.cell(row, col); ok {
+				buf.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+			} else {
+				buf.WriteString("null")
+			}
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func (m Matrix) cell(row, col int) (float32, bool) {
+	v := m.values[row*m.cols+col]
+	if v != v { // NaN
+		return 0, false
+	}
+	return v, true
+}
+
+func (m Matrix) matrixCell(row, col int) MatrixCell {
+	v, ok := m.cell(row, col)
+	return MatrixCell{
+		Source:      index(m.sources, row),
+		Destination: index(m.destinations, col),
+		Value:       v,
+		Reachable:   ok,
+	}
+}
+
+// row returns a row of the source coordinate
+func (m Matrix) row(source int) (int, bool) {
+	return position(m.sources, source, m.rows)
+}
+
+// col returns a column of the destination coordinate
+func (m Matrix) col(destination int) (int, bool) {
+	return position(m.destinations, destination, m.cols)
+}
+
+// position finds a coordinate in the indices, nil indices map every coordinate to itself
+func position(indices []int, coordinate, n int) (int, bool) {
+	if indices == nil {
+		return coordinate, coordinate >= 0 && coordinate < n
+	}
+	for i, c := range indices {
+		if c == coordinate {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func index(indices []int, i int) int {
+	if indices == nil {
+		return i
+	}
+	return indices[i]
+}
+
+func argMin(cells []MatrixCell, coordinate func(MatrixCell) int) (int, bool) {
+	best := -1
+	for i, c := range cells {
+		if c.Reachable && (best < 0 || c.Value < cells[best].Value) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return coordinate(cells[best]), true
+}
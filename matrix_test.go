@@ -0,0 +1,111 @@
+package osrm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrixUnmarshalJSON(t *testing.T) {
+	var m Matrix
+	require.NoError(t, json.Unmarshal([]byte(`[[0,12.5,null],[7,0,3]]`), &m))
+
+	assert.Equal(t, 2, m.Rows())
+	assert.Equal(t, 3, m.Cols())
+
+	v, ok := m.At(0, 1)
+	assert.True(t, ok)
+	assert.Equal(t, float32(12.5), v)
+
+	v, ok = m.At(0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, float32(0), v)
+
+	_, ok = m.At(0, 2)
+	assert.False(t, ok)
+	assert.False(t, m.Reachable(0, 2))
+	assert.False(t, m.Reachable(2, 0))
+	assert.False(t, m.Reachable(-1, 0))
+
+	assert.Equal(t, [][]float32{{0, 12.5, -1}, {7, 0, 3}}, m.Float32s(-1))
+}
+
+func TestMatrixUnmarshalJSONErrors(t *testing.T) {
+	var m Matrix
+	assert.EqualError(t, json.Unmarshal([]byte(`[[0,1],[2]]`), &m), "matrix row 1 has 1 columns, 2 expected")
+	assert.Error(t, json.Unmarshal([]byte(`[0,1]`), &m))
+
+	require.NoError(t, json.Unmarshal([]byte(`null`), &m))
+	assert.Equal(t, 0, m.Rows())
+	assert.Nil(t, m.Float32s(0))
+}
+
+func TestMatrixMarshalJSON(t *testing.T) {
+	var m Matrix
+	require.NoError(t, json.Unmarshal([]byte(`[[0,12.5,null],[7,0,3]]`), &m))
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `[[0,12.5,null],[7,0,3]]`, string(b))
+
+	b, err = json.Marshal(Matrix{})
+	require.NoError(t, err)
+	assert.Equal(t, `null`, string(b))
+}
+
+func TestMatrixWithIndices(t *testing.T) {
+	var m Matrix
+	require.NoError(t, json.Unmarshal([]byte(`[[5,null,2],[1,4,null]]`), &m))
+	m = m.withIndices([]int{3, 1}, nil)
+
+	v, ok := m.At(3, 0)
+	assert.True(t, ok)
+	assert.Equal(t, float32(5), v)
+	v, ok = m.At(1, 1)
+	assert.True(t, ok)
+	assert.Equal(t, float32(4), v)
+	assert.False(t, m.Reachable(0, 0))
+
+	assert.Equal(t, []MatrixCell{
+		{Source: 1, Destination: 0, Value: 1, Reachable: true},
+		{Source: 1, Destination: 1, Value: 4, Reachable: true},
+		{Source: 1, Destination: 2},
+	}, m.Row(1))
+	assert.Nil(t, m.Row(0))
+
+	assert.Equal(t, []MatrixCell{
+		{Source: 3, Destination: 1},
+		{Source: 1, Destination: 1, Value: 4, Reachable: true},
+	}, m.Column(1))
+	assert.Nil(t, m.Column(3))
+}
+
+func TestMatrixArgMin(t *testing.T) {
+	var m Matrix
+	require.NoError(t, json.Unmarshal([]byte(`[[5,null,2],[1,4,null],[null,null,null]]`), &m))
+	m = m.withIndices([]int{2, 4, 6}, []int{1, 3, 5})
+
+	dst, ok := m.ArgMinRow(2)
+	assert.True(t, ok)
+	assert.Equal(t, 5, dst)
+
+	dst, ok = m.ArgMinRow(4)
+	assert.True(t, ok)
+	assert.Equal(t, 1, dst)
+
+	_, ok = m.ArgMinRow(6)
+	assert.False(t, ok)
+
+	src, ok := m.ArgMinColumn(1)
+	assert.True(t, ok)
+	assert.Equal(t, 4, src)
+
+	src, ok = m.ArgMinColumn(5)
+	assert.True(t, ok)
+	assert.Equal(t, 2, src)
+
+	_, ok = m.ArgMinColumn(0)
+	assert.False(t, ok)
+}
@@ -0,0 +1,236 @@
+package osrm
+
+import (
+	"context"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Observation represents a single OSRM call observed by metrics
+type Observation struct {
+	Service string
+	Profile string
+	// Code is an OSRM response code, it is empty if no OSRM response was decoded
+	// (e.g. on transport errors or unexpected http status codes)
+	Code string
+	// StatusCode is an http status code of the last request, zero if there was no response
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	// Coordinates is a number of the request coordinates
+	Coordinates int
+	// Cells is a number of table cells, zero for other services
+	Cells int
+	// Cached reports the call answered from the response cache without an http request
+	Cached bool
+}
+
+// Metrics observes every call, calls short-circuited by the configured interceptors are not observed.
+// Calls answered from the cache are observed as cached ones, calls coalesced with another call are
+// observed with the status code of the shared request. Observe is called concurrently.
+type Metrics interface {
+	Observe(o Observation)
+}
+
+// metricsInterceptor reports the calls to metrics
+func metricsInterceptor(m Metrics) Interceptor {
+	return func(ctx context.Context, call *Call, invoke Invoker) error {
+		start := time.Now()
+		err := invoke(ctx, call)
+
+		o := Observation{
+			Service:     call.Service,
+			Profile:     call.Profile,
+			StatusCode:  call.StatusCode,
+			Err:         err,
+			Duration:    time.Since(start),
+			Coordinates: call.Coordinates.Length(),
+			Cached:      call.cached,
+		}
+		if _, ok := err.(ResponseStatus); ok || err == nil {
+			if r, ok := call.Response.(interface{ ErrCode() string }); ok {
+				o.Code = r.ErrCode()
+			}
+		}
+		if call.Service == "table" {
+			o.Cells = tableSize(call, "sources") * tableSize(call, "destinations")
+		}
+		m.Observe(o)
+		return err
+	}
+}
+
+// tableSize returns a number of table sources or destinations
+func tableSize(call *Call, key string) int {
+	if n := len(call.Options[key]); n > 0 {
+		return n
+	}
+	return call.Coordinates.Length()
+}
+
+// MemoryMetrics keeps all observations in memory, it is meant for tests
+type MemoryMetrics struct {
+	mu           sync.Mutex
+	observations []Observation
+}
+
+// NewMemoryMetrics creates in-memory metrics
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{}
+}
+
+// Observe implements Metrics
+func (m *MemoryMetrics) Observe(o Observation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = append(m.observations, o)
+}
+
+// Observations returns all observations in order
+func (m *MemoryMetrics) Observations() []Observation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Observation(nil), m.observations...)
+}
+
+// Count returns a number of observed calls of the service and profile with the code
+func (m *MemoryMetrics) Count(service, profile, code string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, o := range m.observations {
+		if o.Service == service && o.Profile == profile && o.Code == code {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset removes all observations
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = nil
+}
+
+var (
+	latencyBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	sizeBuckets    = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 10000, 100000}
+)
+
+// ExpvarMetrics publishes metrics with expvar. Every metric is a map keyed by
+// "service:profile" with additional ":code" or ":status" suffixes:
+//
+//	calls       - counters of calls by OSRM codes, "Error" is used for calls without OSRM response
+//	status      - counters of calls by http status codes, calls answered from the cache are not counted
+//	cached      - counters of calls answered from the cache
+//	latency_ms  - histograms of call latencies in milliseconds
+//	coordinates - histograms of numbers of coordinates
+//	cells       - histograms of numbers of table cells
+//
+// Histograms are maps of cumulative "le_<bound>" bucket counters, "count" and "sum".
+type ExpvarMetrics struct {
+	root                                               *expvar.Map
+	calls, status, cached, latency, coordinates, cells *expvar.Map
+
+	mu         sync.Mutex
+	histograms map[*expvar.Map]map[string]*histogram
+}
+
+// histogram is a cumulative histogram published as expvar map
+type histogram struct {
+	buckets []float64
+	le      []*expvar.Int
+	count   *expvar.Int
+	sum     *expvar.Float
+}
+
+// NewExpvarMetrics creates metrics published by the name, it panics if the name is already used
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := newExpvarMetrics()
+	expvar.Publish(name, m.root)
+	return m
+}
+
+// newExpvarMetrics creates metrics without publishing them
+func newExpvarMetrics() *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		root:        new(expvar.Map).Init(),
+		calls:       new(expvar.Map).Init(),
+		status:      new(expvar.Map).Init(),
+		cached:      new(expvar.Map).Init(),
+		latency:     new(expvar.Map).Init(),
+		coordinates: new(expvar.Map).Init(),
+		cells:       new(expvar.Map).Init(),
+		histograms:  map[*expvar.Map]map[string]*histogram{},
+	}
+
+	m.root.Set("calls", m.calls)
+	m.root.Set("status", m.status)
+	m.root.Set("cached", m.cached)
+	m.root.Set("latency_ms", m.latency)
+	m.root.Set("coordinates", m.coordinates)
+	m.root.Set("cells", m.cells)
+	return m
+}
+
+// Observe implements Metrics
+func (m *ExpvarMetrics) Observe(o Observation) {
+	key := o.Service + ":" + o.Profile
+	code := o.Code
+	if code == "" {
+		code = "Error"
+	}
+
+	m.calls.Add(key+":"+code, 1)
+	if o.Cached {
+		m.cached.Add(key, 1)
+	} else {
+		m.status.Add(key+":"+strconv.Itoa(o.StatusCode), 1)
+	}
+	m.histogram(m.latency, key, latencyBuckets).observe(float64(o.Duration) / float64(time.Millisecond))
+	m.histogram(m.coordinates, key, sizeBuckets).observe(float64(o.Coordinates))
+	if o.Cells > 0 {
+		m.histogram(m.cells, key, sizeBuckets).observe(float64(o.Cells))
+	}
+}
+
+func (m *ExpvarMetrics) histogram(parent *expvar.Map, key string, buckets []float64) *histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hs, ok := m.histograms[parent]
+	if !ok {
+		hs = map[string]*histogram{}
+		m.histograms[parent] = hs
+	}
+	if h, ok := hs[key]; ok {
+		return h
+	}
+
+	h := &histogram{buckets: buckets, count: new(expvar.Int), sum: new(expvar.Float)}
+	v := new(expvar.Map).Init()
+	for _, b := range buckets {
+		le := new(expvar.Int)
+		h.le = append(h.le, le)
+		v.Set("le_"+strconv.FormatFloat(b, 'f', -1, 64), le)
+	}
+	v.Set("count", h.count)
+	v.Set("sum", h.sum)
+	parent.Set(key, v)
+	hs[key] = h
+	return h
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.le[i].Add(1)
+		}
+	}
+	h.count.Add(1)
+	h.sum.Add(v)
+}
@@ -0,0 +1,133 @@
+package osrm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsObserveCalls(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RawQuery {
+		case "":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": "NoTable", "message": "No table found"}`))
+		case "sources=0":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"code": "Ok", "durations": [[0, 1, 2], [1, 0, 2]]}`))
+		}
+	}))
+	defer ts.Close()
+
+	metrics := NewMemoryMetrics()
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Metrics: metrics})
+
+	_, err := osrm.Table(context.Background(), TableRequest{Profile: "car", Coordinates: geometry, Sources: []int{0, 1}})
+	require.NoError(t, err)
+	_, err = osrm.Table(context.Background(), TableRequest{Profile: "car", Coordinates: geometry})
+	require.EqualError(t, err, "NoTable - No table found")
+	_, err = osrm.Table(context.Background(), TableRequest{Profile: "bike", Coordinates: geometry, Sources: []int{0}})
+	require.IsType(t, &StatusError{}, err)
+
+	observations := metrics.Observations()
+	require.Len(t, observations, 3)
+
+	assert.Equal(t, "table", observations[0].Service)
+	assert.Equal(t, "car", observations[0].Profile)
+	assert.Equal(t, errorCodeOK, observations[0].Code)
+	assert.Equal(t, http.StatusOK, observations[0].StatusCode)
+	assert.Nil(t, observations[0].Err)
+	assert.Equal(t, 3, observations[0].Coordinates)
+	assert.Equal(t, 6, observations[0].Cells)
+	assert.True(t, observations[0].Duration > 0)
+
+	assert.Equal(t, ErrorCodeNoTable, observations[1].Code)
+	assert.Equal(t, http.StatusBadRequest, observations[1].StatusCode)
+	assert.Equal(t, 9, observations[1].Cells)
+
+	assert.Equal(t, "", observations[2].Code)
+	assert.Equal(t, http.StatusBadGateway, observations[2].StatusCode)
+	assert.Equal(t, err, observations[2].Err)
+
+	assert.Equal(t, 1, metrics.Count("table", "car", errorCodeOK))
+	assert.Equal(t, 1, metrics.Count("table", "car", ErrorCodeNoTable))
+	assert.Equal(t, 0, metrics.Count("table", "bike", errorCodeOK))
+
+	metrics.Reset()
+	assert.Empty(t, metrics.Observations())
+}
+
+func TestMetricsSkipShortCircuitedCalls(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	osrm := NewWithConfig(Config{Metrics: metrics, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			return errors.New("short-circuited")
+		},
+	}})
+
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.Error(t, err)
+	assert.Empty(t, metrics.Observations())
+}
+
+func TestMetricsObserveCacheHits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code": "Ok", "data_version": "v1", "waypoints": []}`))
+	}))
+	defer ts.Close()
+
+	metrics := NewMemoryMetrics()
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Metrics: metrics, Cache: NewLRUCache(10, 0)})
+	for i := 0; i < 2; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+
+	observations := metrics.Observations()
+	require.Len(t, observations, 2)
+	assert.False(t, observations[0].Cached)
+	assert.Equal(t, http.StatusOK, observations[0].StatusCode)
+	assert.True(t, observations[1].Cached)
+	assert.Equal(t, 0, observations[1].StatusCode)
+	assert.Equal(t, 2, metrics.Count("nearest", "car", errorCodeOK))
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	m := newExpvarMetrics()
+	m.Observe(Observation{Service: "table", Profile: "car", Code: "Ok", StatusCode: 200, Duration: 20 * time.Millisecond, Coordinates: 3, Cells: 9})
+	m.Observe(Observation{Service: "table", Profile: "car", StatusCode: 503, Duration: 2 * time.Second, Coordinates: 3, Cells: 9})
+	m.Observe(Observation{Service: "route", Profile: "car", Code: "Ok", Coordinates: 2, Cached: true})
+
+	var v struct {
+		Calls       map[string]int
+		Status      map[string]int
+		Cached      map[string]int
+		Latency     map[string]map[string]float64 `json:"latency_ms"`
+		Coordinates map[string]map[string]float64
+		Cells       map[string]map[string]float64
+	}
+	require.NoError(t, json.Unmarshal([]byte(m.root.String()), &v))
+
+	assert.Equal(t, map[string]int{"table:car:Ok": 1, "table:car:Error": 1, "route:car:Ok": 1}, v.Calls)
+	assert.Equal(t, map[string]int{"table:car:200": 1, "table:car:503": 1}, v.Status)
+	assert.Equal(t, map[string]int{"route:car": 1}, v.Cached)
+
+	latency := v.Latency["table:car"]
+	assert.Equal(t, float64(0), latency["le_10"])
+	assert.Equal(t, float64(1), latency["le_25"])
+	assert.Equal(t, float64(1), latency["le_1000"])
+	assert.Equal(t, float64(2), latency["le_2500"])
+	assert.Equal(t, float64(2), latency["count"])
+	assert.Equal(t, float64(2020), latency["sum"])
+
+	assert.Equal(t, float64(2), v.Coordinates["table:car"]["le_5"])
+	assert.Equal(t, float64(18), v.Cells["table:car"]["sum"])
+}
@@ -0,0 +1,393 @@
+package osrm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol buffers wire types used by Mapbox Vector Tile format
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Vector tile geometry commands
+const (
+	commandMoveTo    = 1
+	commandLineTo    = 2
+	commandClosePath = 7
+)
+
+const defaultTileExtent = 4096
+
+var errTruncatedMessage = errors.New("truncated message")
+
+// pbReader reads a protocol buffers message field by field
+type pbReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *pbReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+// key reads a field key and returns the field number and the wire type
+func (r *pbReader) key() (int, int, error) {
+	k, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(k >> 3), int(k & 0x7), nil
+}
+
+func (r *pbReader) varint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if r.pos >= len(r.buf) {
+			return 0, errTruncatedMessage
+		}
+		b := r.buf[r.pos]
+		r.pos++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("varint overflow")
+}
+
+func (r *pbReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.buf)-r.pos) {
+		return nil, errTruncatedMessage
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *pbReader) fixed32() (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, errTruncatedMessage
+	}
+	b := r.buf[r.pos:]
+	r.pos += 4
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24, nil
+}
+
+func (r *pbReader) fixed64() (uint64, error) {
+	lo, err := r.fixed32()
+	if err != nil {
+		return 0, err
+	}
+	hi, err := r.fixed32()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(lo) | uint64(hi)<<32, nil
+}
+
+// skip skips a value of unknown field
+func (r *pbReader) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("unsupported wire type %d", wire)
+	}
+	return err
+}
+
+// uint32s reads either a packed or a single varint field
+func (r *pbReader) uint32s(wire int, dst []uint32) ([]uint32, error) {
+	if wire == wireVarint {
+		v, err := r.varint()
+		return append(dst, uint32(v)), err
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	packed := pbReader{buf: b}
+	for !packed.done() {
+		v, err := packed.varint()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, uint32(v))
+	}
+	return dst, nil
+}
+
+// decodeTile decodes layers of a vector tile.
+// See https://github.com/mapbox/vector-tile-spec/tree/master/2.1 for details.
+func decodeTile(data []byte) ([]TileLayer, error) {
+	var layers []TileLayer
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tile: %v", err)
+		}
+		if field != 3 || wire != wireBytes {
+			if err := r.skip(wire); err != nil {
+				return nil, fmt.Errorf("failed to decode tile: %v", err)
+			}
+			continue
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tile: %v", err)
+		}
+		layer, err := decodeLayer(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tile layer %d: %v", len(layers), err)
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+func decodeLayer(data []byte) (TileLayer, error) {
+	layer := TileLayer{Version: 1, Extent: defaultTileExtent}
+
+	var (
+		keys     []string
+		values   []interface{}
+		features [][]byte
+	)
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return layer, err
+		}
+		switch {
+		case field == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return layer, err
+			}
+			layer.Name = string(b)
+		case field == 2 && wire == wireBytes:
+			// features refer to keys and values which could be placed after them
+			b, err := r.bytes()
+			if err != nil {
+				return layer, err
+			}
+			features = append(features, b)
+		case field == 3 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return layer, err
+			}
+			keys = append(keys, string(b))
+		case field == 4 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return layer, err
+			}
+			v, err := decodeValue(b)
+			if err != nil {
+				return layer, err
+			}
+			values = append(values, v)
+		case field == 5 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return layer, err
+			}
+			layer.Extent = uint32(v)
+		case field == 15 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return layer, err
+			}
+			layer.Version = uint32(v)
+		default:
+			if err := r.skip(wire); err != nil {
+				return layer, err
+			}
+		}
+	}
+
+	layer.Features = make([]TileFeature, len(features))
+	for i, b := range features {
+		f, err := decodeFeature(b, keys, values)
+		if err != nil {
+			return layer, fmt.Errorf("feature %d: %v", i, err)
+		}
+		layer.Features[i] = f
+	}
+	return layer, nil
+}
+
+func decodeValue(data []byte) (interface{}, error) {
+	var value interface{}
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wire == wireBytes:
+			b, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		case field == 2 && wire == wireFixed32:
+			v, err := r.fixed32()
+			if err != nil {
+				return nil, err
+			}
+			value = math.Float32frombits(v)
+		case field == 3 && wire == wireFixed64:
+			v, err := r.fixed64()
+			if err != nil {
+				return nil, err
+			}
+			value = math.Float64frombits(v)
+		case field == 4 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = int64(v)
+		case field == 5 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		case field == 6 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = int64(v>>1) ^ -int64(v&1)
+		case field == 7 && wire == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			value = v != 0
+		default:
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+func decodeFeature(data []byte, keys []string, values []interface{}) (TileFeature, error) {
+	var (
+		feature  TileFeature
+		tags     []uint32
+		commands []uint32
+	)
+	r := pbReader{buf: data}
+	for !r.done() {
+		field, wire, err := r.key()
+		if err != nil {
+			return feature, err
+		}
+		switch {
+		case field == 1 && wire == wireVarint:
+			feature.ID, err = r.varint()
+		case field == 2 && (wire == wireBytes || wire == wireVarint):
+			tags, err = r.uint32s(wire, tags)
+		case field == 3 && wire == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			feature.Type = TileGeometryType(v)
+		case field == 4 && (wire == wireBytes || wire == wireVarint):
+			commands, err = r.uint32s(wire, commands)
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return feature, err
+		}
+	}
+
+	if len(tags)%2 != 0 {
+		return feature, errors.New("odd number of tags")
+	}
+	feature.Properties = make(map[string]interface{}, len(tags)/2)
+	for i := 0; i < len(tags); i += 2 {
+		k, v := int(tags[i]), int(tags[i+1])
+		if k >= len(keys) || v >= len(values) {
+			return feature, fmt.Errorf("tag %d=%d is out of range", k, v)
+		}
+		feature.Properties[keys[k]] = values[v]
+	}
+
+	geometry, err := decodeGeometry(commands)
+	if err != nil {
+		return feature, err
+	}
+	feature.Geometry = geometry
+	return feature, nil
+}
+
+// decodeGeometry interprets geometry commands with zigzag encoded cursor movements
+func decodeGeometry(commands []uint32) ([][]TilePoint, error) {
+	var (
+		parts  [][]TilePoint
+		cursor TilePoint
+	)
+	for i := 0; i < len(commands); {
+		id, count := commands[i]&0x7, int(commands[i]>>3)
+		i++
+
+		switch id {
+		case commandMoveTo, commandLineTo:
+			if len(commands)-i < 2*count {
+				return nil, fmt.Errorf("command %d expects %d parameters", id, 2*count)
+			}
+			if id == commandLineTo && len(parts) == 0 {
+				return nil, errors.New("line to command without move to")
+			}
+			for n := 0; n < count; n++ {
+				cursor.X += zigzag32(commands[i])
+				cursor.Y += zigzag32(commands[i+1])
+				i += 2
+				if id == commandMoveTo {
+					parts = append(parts, []TilePoint{cursor})
+				} else {
+					parts[len(parts)-1] = append(parts[len(parts)-1], cursor)
+				}
+			}
+		case commandClosePath:
+			if len(parts) == 0 {
+				return nil, errors.New("close path command without move to")
+			}
+			part := parts[len(parts)-1]
+			parts[len(parts)-1] = append(part, part[0])
+		default:
+			return nil, fmt.Errorf("unknown geometry command %d", id)
+		}
+	}
+	return parts, nil
+}
+
+func zigzag32(v uint32) int32 {
+	return int32(v>>1) ^ -int32(v&1)
+}
@@ -4,10 +4,14 @@ import geo "github.com/paulmach/go.geo"
 
 // NearestRequest represents a request to the nearest method
 type NearestRequest struct {
+	GeneralOptions
 	Profile     string
 	Coordinates Geometry
-	Bearings    []Bearing
-	Number      int
+	// Bearings is kept for compatibility, GeneralOptions.Bearings take precedence if they are set.
+	//
+	// Deprecated: use GeneralOptions.Bearings.
+	Bearings []Bearing
+	Number   int
 }
 
 // NearestResponse represents a response from the nearest method
@@ -31,14 +35,10 @@ func (r NearestRequest) request() *request {
 		opts.addInt("number", r.Number)
 	}
 
-	if len(r.Bearings) > 0 {
-		opts.set("bearings", bearings(r.Bearings))
-	}
-
 	return &request{
 		profile: r.Profile,
 		service: "nearest",
 		coords:  r.Coordinates,
-		options: opts,
+		options: r.GeneralOptions.withBearings(r.Bearings).apply(opts),
 	}
 }
@@ -28,3 +28,19 @@ func TestNearestRequestOverviewOption(t *testing.T) {
 		"bearings=60%2C380",
 		req.request().options.encode())
 }
+
+func TestNearestRequestGeneralOptions(t *testing.T) {
+	req := NearestRequest{
+		Number: 2,
+		GeneralOptions: GeneralOptions{
+			Bearings: []Bearing{
+				{60, 380},
+			},
+			Hints: []string{"a"},
+		},
+	}
+	assert.Equal(
+		t,
+		"bearings=60%2C380&hints=a&number=2",
+		req.request().options.encode())
+}
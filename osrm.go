@@ -15,9 +15,11 @@ const (
 
 // OSRM implements the common OSRM API v5.
 // See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md for details.
-// TODO: implement (trip, tile) methods
 type OSRM struct {
 	client
+	format  Format
+	invoker Invoker
+	tables  *tableCache
 }
 
 // Config represents OSRM client configuration options
@@ -28,6 +30,56 @@ type Config struct {
 	// Client is custom pre-configured http client to be used for queries.
 	// New http.Client instance with default settings and one second timeout will be used if not set.
 	Client HTTPClient
+	// Format is a format of OSRM responses. JSON is used if not set.
+	// Flatbuffers format is much faster to decode for large matches. It is used for route, match,
+	// nearest and trip methods. Tables are always requested in JSON, since OSRM encodes unreachable
+	// pairs of flatbuffers tables as zeros, and tiles are always returned as vector tiles.
+	Format Format
+	// RetryPolicy defines how requests failed with transient errors are retried.
+	// Requests are not retried if not set.
+	RetryPolicy RetryPolicy
+	// Pool balances requests between several OSRM servers, ServerURL is ignored if it is set.
+	// The pool is not closed by the client.
+	Pool *Pool
+	// Router sends requests of every profile to its own servers, ServerURL and Pool are ignored if it is set
+	Router *Router
+	// CircuitBreaker fails requests to overloaded servers fast with ErrCircuitOpen.
+	// Circuit breakers are disabled if not set.
+	CircuitBreaker *CircuitBreakerConfig
+	// HedgePolicy sends duplicates of slow requests to cut the latency tail.
+	// Requests are not hedged if not set.
+	HedgePolicy *HedgePolicy
+	// Limiter limits numbers of concurrent HTTP requests, including retries and hedged duplicates,
+	// by priorities set with WithPriority. Requests are not limited if not set.
+	Limiter *Limiter
+	// Interceptors intercept every call, the first interceptor is the outermost one
+	Interceptors []Interceptor
+	// Metrics observes every call passed by Interceptors, including cache hits reported as cached
+	// and calls coalesced with another call.
+	Metrics Metrics
+	// MaxResponseSize limits sizes of response bodies in bytes, larger responses fail
+	// with ErrResponseTooLarge. Responses are not limited if not set.
+	MaxResponseSize int64
+	// Cache stores successful route, table, nearest and match responses. It is purged
+	// when OSRM responds with a data version not seen before, versions of lagging servers
+	// seen earlier are ignored. Responses are not cached if not set.
+	Cache Cache
+	// CacheMaxAge limits the age of responses and table cells served from caches. A data change
+	// is noticed only by a request sent to OSRM, so cached entries could be outdated for this long.
+	// One minute is used if not set.
+	CacheMaxAge time.Duration
+	// CoalesceRequests makes concurrent identical route, table and nearest calls share a single request.
+	// Calls are identical if they have the same URL, headers and priority. The shared request is
+	// canceled only when all its callers are gone.
+	CoalesceRequests bool
+	// TableCache stores table durations and distances by pairs of coordinates, Table then requests
+	// only sources and destinations of missing pairs. Cells expire with the cache entries and when
+	// OSRM data version changes. Table cells are not cached if not set.
+	TableCache Cache
+	// HintStore records hints returned for coordinates of route, table, match and nearest calls
+	// and fills them in later calls of the same profile without their own hints, bearings and radiuses.
+	// Hints are dropped when OSRM data version changes or the server rejects them. Hints are not reused if not set.
+	HintStore Cache
 }
 
 // ResponseStatus represent OSRM API response
@@ -46,6 +98,10 @@ func (r ResponseStatus) Error() string {
 	return r.Code + " - " + r.Message
 }
 
+func (r ResponseStatus) dataVersion() string {
+	return r.DataVersion
+}
+
 func (r ResponseStatus) apiError() error {
 	if r.Code != errorCodeOK {
 		return r
@@ -84,14 +140,81 @@ func NewWithConfig(cfg Config) *OSRM {
 		cfg.Client = &http.Client{Timeout: defaultTimeout}
 	}
 
-	return &OSRM{client: newClient(cfg.ServerURL, cfg.Client)}
+	c := newClient(cfg.ServerURL, cfg.Client)
+	c.retry = cfg.RetryPolicy
+	c.pool = cfg.Pool
+	c.router = cfg.Router
+	c.maxResponseSize = cfg.MaxResponseSize
+	c.limiter = cfg.Limiter
+	if cfg.CircuitBreaker != nil {
+		c.breakers = newBreakers(*cfg.CircuitBreaker)
+	}
+	if cfg.HedgePolicy != nil {
+		c.hedger = newHedger(*cfg.HedgePolicy)
+	}
+
+	o := &OSRM{
+		client: c,
+		format: cfg.Format,
+	}
+	cacheMaxAge := cfg.CacheMaxAge
+	if cacheMaxAge <= 0 {
+		cacheMaxAge = defaultCacheMaxAge
+	}
+	if cfg.TableCache != nil {
+		o.tables = newTableCache(cfg.TableCache, cacheMaxAge)
+	}
+	interceptors := append([]Interceptor(nil), cfg.Interceptors...)
+	if cfg.Metrics != nil {
+		interceptors = append(interceptors, metricsInterceptor(cfg.Metrics))
+	}
+	if cfg.Cache != nil {
+		interceptors = append(interceptors, newResponseCache(cfg.Cache, cacheMaxAge).intercept)
+	}
+	if cfg.HintStore != nil {
+		interceptors = append(interceptors, newHintStore(cfg.HintStore).intercept)
+	}
+	if cfg.CoalesceRequests {
+		interceptors = append(interceptors, newCoalescer().intercept)
+	}
+	if len(interceptors) > 0 {
+		o.invoker = chain(interceptors, o.invoke)
+	}
+	return o
 }
 
 func (o OSRM) query(ctx context.Context, in *request, out response) error {
-	if err := o.client.doRequest(ctx, in, out); err != nil {
+	if _, ok := out.(flatbuffersResponse); ok && o.format == FormatFlatbuffers && in.format == "" {
+		in.format = formatFlatbuffers
+	}
+
+	call := newCall(in, out)
+	invoke := o.invoke
+	if o.invoker != nil {
+		invoke = o.invoker
+	}
+	err := invoke(ctx, call)
+
+	if stats, ok := ctx.Value(callStatsKey{}).(*CallStats); ok {
+		*stats = call.Stats
+	}
+	return err
+}
+
+// invoke makes the call after all interceptors
+func (o OSRM) invoke(ctx context.Context, call *Call) error {
+	in := call.sync()
+
+	call.begin()
+	err := o.client.doRequest(ctx, in, call.Response)
+	call.finish()
+	if err != nil {
 		return err
 	}
-	return out.apiError()
+	if r, ok := call.Response.(response); ok {
+		return r.apiError()
+	}
+	return nil
 }
 
 // Route searches the shortest path between given coordinates.
@@ -107,10 +230,19 @@ func (o OSRM) Route(ctx context.Context, r RouteRequest) (*RouteResponse, error)
 // Table computes duration tables for the given locations.
 // See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md#table-service for details.
 func (o OSRM) Table(ctx context.Context, r TableRequest) (*TableResponse, error) {
+	if o.tables != nil {
+		return o.tables.table(ctx, r, o.table)
+	}
+	return o.table(ctx, r)
+}
+
+func (o OSRM) table(ctx context.Context, r TableRequest) (*TableResponse, error) {
 	var resp TableResponse
 	if err := o.query(ctx, r.request(), &resp); err != nil {
 		return nil, err
 	}
+	resp.Durations = resp.Durations.withIndices(r.Sources, r.Destinations)
+	resp.Distances = resp.Distances.withIndices(r.Sources, r.Destinations)
 	return &resp, nil
 }
 
@@ -121,6 +253,7 @@ func (o OSRM) Match(ctx context.Context, r MatchRequest) (*MatchResponse, error)
 	if err := o.query(ctx, r.request(), &resp); err != nil {
 		return nil, err
 	}
+	resp.waypoints = r.Waypoints
 	return &resp, nil
 }
 
@@ -133,3 +266,25 @@ func (o OSRM) Nearest(ctx context.Context, r NearestRequest) (*NearestResponse,
 	}
 	return &resp, nil
 }
+
+// Trip solves the traveling salesman problem for given coordinates using a greedy heuristic.
+// See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md#trip-service for details.
+func (o OSRM) Trip(ctx context.Context, r TripRequest) (*TripResponse, error) {
+	var resp TripResponse
+	if err := o.query(ctx, r.request(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Tile returns a vector tile with the road network of the given profile.
+// The tile is addressed by zoom and XYZ coordinates, OSRM serves tiles for zoom levels 12 and higher.
+// See https://github.com/Project-OSRM/osrm-backend/blob/master/docs/http.md#tile-service for details.
+func (o OSRM) Tile(ctx context.Context, profile string, z, x, y int) (*Tile, error) {
+	var resp tileResponse
+	if err := o.query(ctx, tileRequest(profile, z, x, y), &resp); err != nil {
+		return nil, err
+	}
+	resp.Tile.X, resp.Tile.Y, resp.Tile.Z = x, y, z
+	return &resp.Tile, nil
+}
@@ -100,6 +100,15 @@ func TestErrorOnRequest(t *testing.T) {
 		assert(t, err)
 	})
 
+	t.Run("trip", func(t *testing.T) {
+		_, err := osrm.Trip(context.Background(), TripRequest{
+			Profile:     "car",
+			Coordinates: geom,
+		})
+
+		assert(t, err)
+	})
+
 	t.Run("nearest", func(t *testing.T) {
 		_, err := osrm.Nearest(context.Background(), NearestRequest{
 			Profile:     "car",
@@ -182,10 +191,80 @@ func TestTableRequest(t *testing.T) {
 	require.NoError(err)
 	require.NotNil(r)
 
-	require.Len(r.Durations, 3)
-	require.Equal([]float32{0, 39, 46.8}, r.Durations[0])
-	require.Equal([]float32{39.5, 0, 34.2}, r.Durations[1])
-	require.Equal([]float32{47.2, 34.2, 0}, r.Durations[2])
+	require.Equal([][]float32{
+		{0, 39, 46.8},
+		{39.5, 0, 34.2},
+		{47.2, 34.2, 0},
+	}, r.Durations.Float32s(-1))
+}
+
+func TestTableRequestWithAnnotations(t *testing.T) {
+	ts := httptest.NewServer(fixturedHTTPHandler("table_response_annotations", func(path, query string) {
+		assert.Equal(t, "/table/v1/car/polyline({aowFrerbM}PbI~Jyd@)", path)
+		assert.Equal(t, "annotations=duration%2Cdistance&fallback_coordinate=snapped&fallback_speed=10&sources=0;1", query)
+	}))
+	defer ts.Close()
+
+	osrm := NewFromURL(ts.URL)
+
+	r, err := osrm.Table(context.Background(), TableRequest{
+		Profile:            "car",
+		Coordinates:        geometry,
+		Sources:            []int{0, 1},
+		Annotations:        TableAnnotationsDurationDistance,
+		FallbackSpeed:      10,
+		FallbackCoordinate: FallbackCoordinateSnapped,
+	})
+
+	require := require.New(t)
+
+	require.NoError(err)
+	require.NotNil(r)
+
+	require.Equal(2, r.Durations.Rows())
+	require.Equal([][]float32{{0, 318.2, 501.9}, {324.5, 0, 286.1}}, r.Distances.Float32s(-1))
+	d, ok := r.Durations.At(1, 2)
+	require.True(ok)
+	require.Equal(float32(34.2), d)
+	require.False(r.Durations.Reachable(2, 0))
+	require.Len(r.Sources, 2)
+	require.Len(r.Destinations, 3)
+	require.Equal(*geo.NewPoint(-73.985746, 40.715655), r.Destinations[2].Location)
+	require.Equal([][2]int{{1, 2}}, r.FallbackSpeedCells)
+}
+
+func TestTableRequestWithFlatbuffersFormat(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// tables are requested in JSON to tell unreachable pairs apart from zero durations
+		assert.Equal(t, "/table/v1/car/polyline({aowFrerbM}PbI~Jyd@)", r.URL.Path)
+		fmt.Fprint(w, `{"code": "Ok", "durations": [[0, null], [39.5, 0]]}`)
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Format: FormatFlatbuffers})
+
+	r, err := osrm.Table(context.Background(), TableRequest{Profile: "car", Coordinates: geometry})
+
+	require := require.New(t)
+
+	require.NoError(err)
+	require.NotNil(r)
+	require.False(r.Durations.Reachable(0, 1))
+	require.Equal([][]float32{{0, -1}, {39.5, 0}}, r.Durations.Float32s(-1))
+}
+
+func TestRequestInFlatbuffersFormatWithJSONError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write(fixturedJSON("invalid_query_response"))
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Format: FormatFlatbuffers})
+
+	_, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: geometry})
+	require.EqualError(t, err, "InvalidQuery - Query string malformed close to position 28")
 }
 
 func TestMatchRequest(t *testing.T) {
@@ -219,6 +298,87 @@ func TestMatchRequest(t *testing.T) {
 	require.Len(matching.Legs, 2)
 	require.Len(matching.Legs[0].Annotation.Nodes, 11)
 	require.Len(matching.Legs[1].Annotation.Nodes, 15)
+	// tracepoints
+	points := r.MatchedPoints()
+	require.Len(points, 3)
+	require.Equal(0, points[0].LegIndex)
+	require.Equal(1, points[1].LegIndex)
+	require.Equal(1, points[2].LegIndex)
+	require.Equal(*geo.NewPoint(-73.985746, 40.715655), points[2].Location)
+}
+
+func TestTripRequest(t *testing.T) {
+	ts := httptest.NewServer(fixturedHTTPHandler("trip_response_full", func(path, query string) {
+		assert.Equal(t, "/trip/v1/car/polyline({aowFrerbM}PbI~Jyd@)", path)
+		assert.Equal(t, "geometries=polyline6&roundtrip=true&source=first", query)
+	}))
+	defer ts.Close()
+
+	osrm := NewFromURL(ts.URL)
+
+	r, err := osrm.Trip(context.Background(), TripRequest{
+		Profile:     "car",
+		Coordinates: geometry,
+		Roundtrip:   RoundtripTrue,
+		Source:      SourceFirst,
+	})
+
+	require := require.New(t)
+
+	require.NoError(err)
+	require.NotNil(r)
+
+	// response
+	require.Equal("2017-11-17T21:43:02Z", r.DataVersion)
+	// trips
+	require.Len(r.Trips, 1)
+	trip := r.Trips[0]
+	require.Equal(float32(1869.5), trip.Distance)
+	require.Equal(float32(142.6), trip.Duration)
+	require.Len(trip.Legs, 3)
+	// waypoints
+	require.Len(r.Waypoints, 3)
+	require.Equal(0, r.Waypoints[1].TripsIndex)
+	require.Equal(2, r.Waypoints[1].WaypointIndex)
+	require.Equal(1, r.Waypoints[2].WaypointIndex)
+	require.Equal(*geo.NewPoint(-73.985746, 40.715655), r.Waypoints[2].Location)
+}
+
+func TestTileRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tile/v1/car/tile(4823,6160,14).mvt", r.URL.Path)
+		_, _ = w.Write(tileFixture())
+	}))
+	defer ts.Close()
+
+	osrm := NewFromURL(ts.URL)
+
+	tile, err := osrm.Tile(context.Background(), "car", 14, 4823, 6160)
+
+	require := require.New(t)
+
+	require.NoError(err)
+	require.NotNil(tile)
+
+	require.Equal(4823, tile.X)
+	require.Equal(6160, tile.Y)
+	require.Equal(14, tile.Z)
+	require.Len(tile.Layers, 2)
+	require.Len(tile.Speeds().Features, 1)
+	require.Len(tile.Turns().Features, 1)
+}
+
+func TestTileRequestWithError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write(fixturedJSON("invalid_query_response"))
+	}))
+	defer ts.Close()
+
+	osrm := NewFromURL(ts.URL)
+
+	_, err := osrm.Tile(context.Background(), "car", 14, 4823, 6160)
+	require.EqualError(t, err, "InvalidQuery - Query string malformed close to position 28")
 }
 
 func TestNearestRequest(t *testing.T) {
@@ -0,0 +1,267 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	geo "github.com/paulmach/go.geo"
+)
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthCheckProfile  = "car"
+	defaultMaxFailures         = 3
+	defaultEjectionDuration    = 30 * time.Second
+	latencyDecay               = 0.3
+)
+
+// PoolConfig represents configuration options of a pool of OSRM servers
+type PoolConfig struct {
+	// ServerURLs are URLs of OSRM servers serving the same data
+	ServerURLs []string
+	// Client is an http client used for health checks.
+	// New http.Client instance with one second timeout will be used if not set.
+	Client HTTPClient
+	// Strategy picks a server for every request, round-robin is used if not set
+	Strategy Strategy
+	// HealthCheckInterval is an interval between active health checks, 5s is used if not set.
+	// Active health checks are disabled if it is negative.
+	HealthCheckInterval time.Duration
+	// HealthCheck is a cheap nearest query sent to every server as an active health check.
+	// A server is healthy if it responds with an OSRM response, even with an API error.
+	// A query for (0, 0) coordinate of car profile is used if not set.
+	HealthCheck NearestRequest
+	// MaxFailures is a number of consecutive failed requests after which a server
+	// is ejected from the pool, 3 is used if not set
+	MaxFailures int
+	// EjectionDuration is a time for which a failed server is ejected, 30s is used if not set
+	EjectionDuration time.Duration
+}
+
+// Pool balances requests between several OSRM servers, skipping servers that failed
+// health checks or recent requests. If no server is available, all of them are used.
+// A pool should be closed to stop its health checks.
+type Pool struct {
+	backends         []*Backend
+	strategy         Strategy
+	maxFailures      int64
+	ejectionDuration time.Duration
+
+	// ctx is canceled on close to stop health checks
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Backend represents an OSRM server of a pool
+type Backend struct {
+	url string
+
+	outstanding  int64
+	latency      int64 // EWMA in nanoseconds
+	failures     int64
+	ejectedUntil int64 // unix nanoseconds
+	unhealthy    int32
+}
+
+// NewPool creates a pool of OSRM servers and starts its health checks.
+// Requests to a pool without servers fail with ErrEmptyPool.
+func NewPool(cfg PoolConfig) *Pool {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if cfg.Strategy == nil {
+		cfg.Strategy = &RoundRobinStrategy{}
+	}
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if cfg.HealthCheck.Profile == "" {
+		cfg.HealthCheck.Profile = defaultHealthCheckProfile
+	}
+	if len(cfg.HealthCheck.Coordinates.PointSet) == 0 {
+		cfg.HealthCheck.Coordinates = NewGeometryFromPointSet(geo.PointSet{{0, 0}})
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultMaxFailures
+	}
+	if cfg.EjectionDuration <= 0 {
+		cfg.EjectionDuration = defaultEjectionDuration
+	}
+
+	p := &Pool{
+		strategy:         cfg.Strategy,
+		maxFailures:      int64(cfg.MaxFailures),
+		ejectionDuration: cfg.EjectionDuration,
+		done:             make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	for _, u := range cfg.ServerURLs {
+		p.backends = append(p.backends, &Backend{url: u})
+	}
+
+	if cfg.HealthCheckInterval > 0 {
+		go p.checkHealth(cfg.Client, cfg.HealthCheck, cfg.HealthCheckInterval)
+	} else {
+		close(p.done)
+	}
+	return p
+}
+
+// Backends returns all servers of the pool
+func (p *Pool) Backends() []*Backend {
+	return append([]*Backend(nil), p.backends...)
+}
+
+// Close stops health checks of the pool
+func (p *Pool) Close() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// errNoUsableServer is returned by acquire without fallback if no server is available and usable
+var errNoUsableServer = errors.New("osrm5: no usable server in the pool")
+
+// acquire picks a usable server for a request, release must be called once the request is done.
+// All servers are used if none is available and usable with fallback, otherwise acquire fails.
+func (p *Pool) acquire(usable func(*Backend) bool, fallback bool) (*Backend, error) {
+	now := time.Now()
+	available := make([]*Backend, 0, len(p.backends))
+	for _, b := range p.backends {
+		if b.available(now) && usable(b) {
+			available = append(available, b)
+		}
+	}
+	if len(available) == 0 {
+		if !fallback && len(p.backends) > 0 {
+			return nil, errNoUsableServer
+		}
+		available = p.backends
+	}
+
+	b := p.strategy.Pick(available)
+	if b == nil {
+		return nil, ErrEmptyPool
+	}
+	atomic.AddInt64(&b.outstanding, 1)
+	return b, nil
+}
+
+// release records a result of a request to the server made within the attempt context.
+// Attempt timeouts are failures of the server, unlike cancellations.
+func (p *Pool) release(ctx, attemptCtx context.Context, b *Backend, start time.Time, err error) {
+	atomic.AddInt64(&b.outstanding, -1)
+	if err == ErrCircuitOpen {
+		return // the request has not been made
+	}
+	if err != nil && (ctx.Err() != nil || attemptCtx.Err() == context.Canceled) {
+		return // the request was abandoned by the caller or lost to its duplicate, failures are kept
+	}
+
+	if err != nil && isBackendFailure(err) {
+		if atomic.AddInt64(&b.failures, 1) >= p.maxFailures {
+			atomic.StoreInt64(&b.ejectedUntil, time.Now().Add(p.ejectionDuration).UnixNano())
+			atomic.StoreInt64(&b.failures, 0)
+		}
+		return
+	}
+
+	atomic.StoreInt64(&b.failures, 0)
+	if err == nil {
+		b.observe(time.Since(start))
+	}
+}
+
+func (p *Pool) checkHealth(c HTTPClient, check NearestRequest, interval time.Duration) {
+	defer close(p.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, b := range p.backends {
+			wg.Add(1)
+			go func(b *Backend) {
+				defer wg.Done()
+				b.check(p.ctx, c, check, interval)
+			}(b)
+		}
+		wg.Wait()
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// URL returns the server URL
+func (b *Backend) URL() string {
+	return b.url
+}
+
+// Outstanding returns a number of requests to the server in progress
+func (b *Backend) Outstanding() int {
+	return int(atomic.LoadInt64(&b.outstanding))
+}
+
+// Latency returns an exponentially weighted moving average of the server latency
+func (b *Backend) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&b.latency))
+}
+
+// Healthy reports whether the server passed the last health check and is not ejected for failures
+func (b *Backend) Healthy() bool {
+	return b.available(time.Now())
+}
+
+func (b *Backend) available(now time.Time) bool {
+	return atomic.LoadInt32(&b.unhealthy) == 0 && now.UnixNano() >= atomic.LoadInt64(&b.ejectedUntil)
+}
+
+func (b *Backend) observe(latency time.Duration) {
+	for {
+		old := atomic.LoadInt64(&b.latency)
+		next := int64(latency)
+		if old > 0 {
+			next = int64(latencyDecay*float64(latency) + (1-latencyDecay)*float64(old))
+		}
+		if atomic.CompareAndSwapInt64(&b.latency, old, next) {
+			return
+		}
+	}
+}
+
+func (b *Backend) check(ctx context.Context, c HTTPClient, check NearestRequest, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var unhealthy int32
+	in := check.request()
+	url, err := in.URL(b.url)
+	if err == nil {
+		err = newClient(b.url, c).attempt(ctx, url, in, &NearestResponse{})
+	}
+	if ctx.Err() == context.Canceled {
+		return // the pool is closed
+	}
+	if err != nil {
+		unhealthy = 1
+	}
+	atomic.StoreInt32(&b.unhealthy, unhealthy)
+}
+
+// isBackendFailure tells errors caused by the server apart from the ones caused by the request
+func isBackendFailure(err error) bool {
+	if e, ok := err.(*StatusError); ok {
+		return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+	}
+	return isRetryable(err)
+}
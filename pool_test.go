@@ -0,0 +1,159 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nearestServer(calls *int32, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+}
+
+func TestPoolBalancesRequests(t *testing.T) {
+	var calls1, calls2 int32
+	ts1, ts2 := nearestServer(&calls1, http.StatusOK), nearestServer(&calls2, http.StatusOK)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts1.URL, ts2.URL}, HealthCheckInterval: -1})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool})
+	for i := 0; i < 4; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls1))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls2))
+	for _, b := range pool.Backends() {
+		assert.True(t, b.Healthy())
+		assert.True(t, b.Latency() > 0)
+		assert.Equal(t, 0, b.Outstanding())
+	}
+}
+
+func TestPoolEjectsFailedBackend(t *testing.T) {
+	var calls1, calls2 int32
+	ts1, ts2 := nearestServer(&calls1, http.StatusServiceUnavailable), nearestServer(&calls2, http.StatusOK)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts1.URL, ts2.URL}, HealthCheckInterval: -1, MaxFailures: 2})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool, RetryPolicy: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}})
+	for i := 0; i < 6; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls1))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&calls2))
+	assert.False(t, pool.Backends()[0].Healthy())
+	assert.True(t, pool.Backends()[1].Healthy())
+}
+
+func TestPoolKeepsFailuresOfCanceledRequests(t *testing.T) {
+	pool := NewPool(PoolConfig{ServerURLs: []string{"http://a"}, HealthCheckInterval: -1, MaxFailures: 2})
+	defer pool.Close()
+	release := func(ctx, attemptCtx context.Context, err error) {
+		b, acquireErr := pool.acquire(func(*Backend) bool { return true }, true)
+		require.NoError(t, acquireErr)
+		pool.release(ctx, attemptCtx, b, time.Now(), err)
+	}
+	failure := &StatusError{StatusCode: http.StatusServiceUnavailable}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	release(context.Background(), context.Background(), failure)
+	// neither the caller's cancellation nor a lost hedge race resets the counter
+	release(canceled, canceled, context.Canceled)
+	release(context.Background(), canceled, context.Canceled)
+	assert.True(t, pool.Backends()[0].Healthy())
+
+	release(context.Background(), context.Background(), failure)
+	assert.False(t, pool.Backends()[0].Healthy())
+}
+
+func TestPoolUsesAllBackendsIfNoneAvailable(t *testing.T) {
+	var calls int32
+	ts := nearestServer(&calls, http.StatusBadGateway)
+	defer ts.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts.URL}, HealthCheckInterval: -1, MaxFailures: 1})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool})
+	for i := 0; i < 2; i++ {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestEmptyPool(t *testing.T) {
+	pool := NewPool(PoolConfig{HealthCheckInterval: -1})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool, RetryPolicy: RetryPolicy{MaxAttempts: 2}})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	assert.Equal(t, ErrEmptyPool, err)
+}
+
+func TestPoolDoesNotEjectOnAPIErrors(t *testing.T) {
+	var calls int32
+	ts := nearestServer(&calls, http.StatusBadRequest)
+	defer ts.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts.URL}, HealthCheckInterval: -1, MaxFailures: 1})
+	defer pool.Close()
+
+	osrm := NewWithConfig(Config{Pool: pool})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	assert.True(t, pool.Backends()[0].Healthy())
+}
+
+func TestPoolHealthChecks(t *testing.T) {
+	var healthy int32 = 1
+	checks := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks <- r.URL.Path
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code": "NoSegment", "message": "Could not find a matching segment for any coordinate"}`)
+	}))
+	defer ts.Close()
+
+	pool := NewPool(PoolConfig{ServerURLs: []string{ts.URL}, HealthCheckInterval: 10 * time.Millisecond})
+	defer pool.Close()
+
+	assert.Equal(t, "/nearest/v1/car/polyline(??)", <-checks)
+	<-checks // the result of the first check is stored before the second one
+	assert.True(t, pool.Backends()[0].Healthy())
+
+	atomic.StoreInt32(&healthy, 0)
+	for i := 0; i < 3; i++ {
+		<-checks // a check in progress may have started before the change
+	}
+	assert.False(t, pool.Backends()[0].Healthy())
+
+	require.NoError(t, pool.Close())
+	require.NoError(t, pool.Close())
+}
@@ -0,0 +1,169 @@
+package osrm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 2 * time.Second
+	defaultMultiplier     = 2
+	defaultJitter         = 0.2
+)
+
+// RetryPolicy defines how requests failed with transient errors are retried.
+// Timeouts, connection resets, refused connections and 429, 502, 503 and 504 status codes
+// are retried, while OSRM API errors (e.g. InvalidQuery or NoRoute) are returned at once.
+// The zero value makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is a maximum number of attempts including the first one
+	MaxAttempts int
+	// InitialBackoff is a delay before the first retry, 100ms is used if not set
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, 2s is used if not set
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry, 2 is used if not set
+	Multiplier float64
+	// Jitter is a fraction of the delay randomly added or subtracted, 0.2 is used if not set.
+	// A negative value disables jitter.
+	Jitter float64
+	// PerAttemptTimeout limits every attempt within the context deadline, attempts are limited
+	// by the context only if not set
+	PerAttemptTimeout time.Duration
+}
+
+// StatusError is returned when OSRM responds with an unexpected http status code
+type StatusError struct {
+	StatusCode int
+	// Body is an excerpt of the response body
+	Body      []byte
+	truncated bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected http status code %d with body %s", e.StatusCode, quoteExcerpt(e.Body, e.truncated))
+}
+
+// readBodyError is returned when a response body could not be read
+type readBodyError struct {
+	err error
+}
+
+func (e *readBodyError) Error() string {
+	return "failed to read body: " + e.err.Error()
+}
+
+func (e *readBodyError) Unwrap() error {
+	return e.err
+}
+
+// do calls the attempt until it succeeds, fails with a permanent error or attempts are exhausted
+func (p RetryPolicy) do(ctx context.Context, attempt func(ctx context.Context) error) error {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+
+	for n := 1; ; n++ {
+		err := p.attempt(ctx, attempt)
+		if err == nil || n >= p.MaxAttempts || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+
+		delay := p.jitter(backoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err // the next attempt would not fit into the deadline anyway
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		backoff = p.next(backoff)
+	}
+}
+
+func (p RetryPolicy) attempt(ctx context.Context, attempt func(ctx context.Context) error) error {
+	if p.PerAttemptTimeout <= 0 {
+		return attempt(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.PerAttemptTimeout)
+	defer cancel()
+	return attempt(ctx)
+}
+
+func (p RetryPolicy) next(backoff time.Duration) time.Duration {
+	multiplier, max := p.Multiplier, p.MaxBackoff
+	if multiplier <= 0 {
+		multiplier = defaultMultiplier
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	return time.Duration(math.Min(float64(backoff)*multiplier, float64(max)))
+}
+
+func (p RetryPolicy) jitter(backoff time.Duration) time.Duration {
+	jitter := p.Jitter
+	if jitter < 0 {
+		return backoff
+	}
+	if jitter == 0 {
+		jitter = defaultJitter
+	}
+	return time.Duration(float64(backoff) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// isRetryable tells transient errors apart from permanent ones. Errors of the http client
+// and errors implementing Unwrap are unwrapped.
+func isRetryable(err error) bool {
+	for {
+		switch e := err.(type) {
+		case *StatusError:
+			switch e.StatusCode {
+			case http.StatusTooManyRequests, http.StatusBadGateway,
+				http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				return true
+			}
+			return false
+		case *readBodyError:
+			err = e.err
+		case *url.Error:
+			if e.Timeout() {
+				return true
+			}
+			err = e.Err
+		case *net.OpError:
+			if e.Timeout() {
+				return true
+			}
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case syscall.Errno:
+			return e == syscall.ECONNRESET || e == syscall.ECONNREFUSED || e == syscall.EPIPE
+		case net.Error:
+			return e.Timeout()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			// an attempt timeout while the caller's context is still alive, or a connection
+			// closed by a restarting server
+			return err == context.DeadlineExceeded || err == io.EOF || err == io.ErrUnexpectedEOF
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+func TestRetryPolicyRetriesTransientStatus(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: testRetryPolicy})
+	r, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "bad gateway")
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: testRetryPolicy})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+
+	require.EqualError(t, err, "unexpected http status code 502 with body \"bad gateway\"")
+	require.IsType(t, &StatusError{}, err)
+	assert.Equal(t, http.StatusBadGateway, err.(*StatusError).StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyDoesNotRetryAPIErrors(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code": "NoRoute", "message": "Impossible route between points"}`)
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: testRetryPolicy})
+	_, err := osrm.Route(context.Background(), RouteRequest{Profile: "car", Coordinates: geometry})
+
+	require.EqualError(t, err, "NoRoute - Impossible route between points")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyPerAttemptTimeout(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, `{"code": "Ok", "waypoints": []}`)
+	}))
+	defer ts.Close()
+
+	policy := testRetryPolicy
+	policy.PerAttemptTimeout = 50 * time.Millisecond
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: policy})
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyStopsOnCanceledContext(t *testing.T) {
+	var calls int32
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: testRetryPolicy})
+	_, err := osrm.Nearest(ctx, NearestRequest{Profile: "car", Coordinates: geometry})
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Jitter: 0.1}
+
+	assert.Equal(t, 200*time.Millisecond, p.next(100*time.Millisecond))
+	assert.Equal(t, 300*time.Millisecond, p.next(200*time.Millisecond))
+
+	for i := 0; i < 100; i++ {
+		d := p.jitter(100 * time.Millisecond)
+		assert.True(t, d >= 90*time.Millisecond && d <= 110*time.Millisecond, d)
+	}
+
+	p.Jitter = -1
+	assert.Equal(t, 100*time.Millisecond, p.jitter(100*time.Millisecond))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// wrappedError wraps an error the way fmt.Errorf does with %w
+type wrappedError struct {
+	err error
+}
+
+func (e wrappedError) Error() string { return "wrapped: " + e.err.Error() }
+func (e wrappedError) Unwrap() error { return e.err }
+
+func Test_isRetryable(t *testing.T) {
+	reset := &url.Error{Op: "Get", URL: "/", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+	refused := &url.Error{Op: "Get", URL: "/", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+
+	for _, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{&StatusError{StatusCode: http.StatusBadGateway}, true},
+		{&StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{&StatusError{StatusCode: http.StatusGatewayTimeout}, true},
+		{&StatusError{StatusCode: http.StatusInternalServerError}, false},
+		{&StatusError{StatusCode: http.StatusNotFound}, false},
+		{reset, true},
+		{refused, true},
+		{&url.Error{Op: "Get", URL: "/", Err: timeoutError{}}, true},
+		{&url.Error{Op: "Get", URL: "/", Err: io.EOF}, true},
+		{&readBodyError{io.ErrUnexpectedEOF}, true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{ResponseStatus{Code: ErrorCodeInvalidQuery}, false},
+		{errors.New("failed to unmarshal body"), false},
+		{wrappedError{&StatusError{StatusCode: http.StatusServiceUnavailable}}, true},
+		{wrappedError{&StatusError{StatusCode: http.StatusNotFound}}, false},
+		{wrappedError{reset}, true},
+		{&readBodyError{wrappedError{context.DeadlineExceeded}}, true},
+		{wrappedError{context.Canceled}, false},
+	} {
+		assert.Equal(t, tc.retryable, isRetryable(tc.err), tc.err.Error())
+	}
+}
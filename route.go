@@ -2,6 +2,7 @@ package osrm
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 
 	geo "github.com/paulmach/go.geo"
@@ -9,8 +10,12 @@ import (
 
 // RouteRequest represents a request to the route method
 type RouteRequest struct {
-	Profile          string
-	Coordinates      Geometry
+	GeneralOptions
+	Profile     string
+	Coordinates Geometry
+	// Bearings is kept for compatibility, GeneralOptions.Bearings take precedence if they are set.
+	//
+	// Deprecated: use GeneralOptions.Bearings.
 	Bearings         []Bearing
 	Steps            Steps
 	Annotations      Annotations
@@ -18,6 +23,7 @@ type RouteRequest struct {
 	Geometries       Geometries
 	ContinueStraight ContinueStraight
 	Waypoints        []int
+	Alternatives     Alternatives
 }
 
 // RouteResponse represents a response from the route method
@@ -27,6 +33,88 @@ type RouteResponse struct {
 	Waypoints []Waypoint `json:"waypoints"`
 }
 
+// Fastest returns the route with the least duration or nil if there are no routes
+func (r RouteResponse) Fastest() *Route {
+	return r.pick(func(a, b *Route) bool { return a.Duration < b.Duration })
+}
+
+// Shortest returns the route with the least distance or nil if there are no routes
+func (r RouteResponse) Shortest() *Route {
+	return r.pick(func(a, b *Route) bool { return a.Distance < b.Distance })
+}
+
+// MostDifferent returns the alternative route sharing the least part of its geometry with the primary (first) route.
+// It returns nil if there are no alternatives. Routes should be requested with full overview for accurate results.
+func (r RouteResponse) MostDifferent() *Route {
+	if len(r.Routes) < 2 {
+		return nil
+	}
+
+	primary := r.Routes[0].Geometry
+	best, bestDivergence := 1, -1.0
+	for i := 1; i < len(r.Routes); i++ {
+		if d := divergence(r.Routes[i].Geometry, primary); d > bestDivergence {
+			best, bestDivergence = i, d
+		}
+	}
+	return &r.Routes[best]
+}
+
+// pick returns the first route that is not less than any other route
+func (r RouteResponse) pick(less func(a, b *Route) bool) *Route {
+	if len(r.Routes) == 0 {
+		return nil
+	}
+
+	best := &r.Routes[0]
+	for i := 1; i < len(r.Routes); i++ {
+		if less(&r.Routes[i], best) {
+			best = &r.Routes[i]
+		}
+	}
+	return best
+}
+
+// overlapDistance is a distance in meters within which two geometries are considered to follow the same road
+const overlapDistance = 15.0
+
+// divergence returns a share of the geometry length lying farther than overlapDistance from the other geometry
+func divergence(g, other Geometry) float64 {
+	var total, diverged float64
+	for i := 1; i < g.Length(); i++ {
+		segment := geo.NewLine(g.GetAt(i-1), g.GetAt(i))
+		length := segment.GeoDistance()
+		total += length
+		if distanceTo(other, segment.Midpoint()) > overlapDistance {
+			diverged += length
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return diverged / total
+}
+
+// distanceTo returns the distance in meters from the point to the closest point of the geometry
+func distanceTo(g Geometry, p *geo.Point) float64 {
+	switch g.Length() {
+	case 0:
+		return math.Inf(1)
+	case 1:
+		return p.GeoDistanceFrom(g.GetAt(0))
+	}
+
+	min := math.Inf(1)
+	for i := 1; i < g.Length(); i++ {
+		segment := geo.NewLine(g.GetAt(i-1), g.GetAt(i))
+		closest := segment.Interpolate(math.Max(0, math.Min(1, segment.Project(p))))
+		if d := p.GeoDistanceFrom(closest); d < min {
+			min = d
+		}
+	}
+	return min
+}
+
 type Waypoint struct {
 	Name     string    `json:"name"`
 	Location geo.Point `json:"location"`
@@ -56,27 +144,83 @@ type RouteLeg struct {
 
 // Annotation contains additional metadata for each coordinate along the route geometry
 type Annotation struct {
-	Duration []float32 `json:"duration,omitempty"`
-	Distance []float32 `json:"distance,omitempty"`
-	Nodes    []uint64  `json:"nodes,omitempty"`
+	Duration    []float32           `json:"duration,omitempty"`
+	Distance    []float32           `json:"distance,omitempty"`
+	Speed       []float32           `json:"speed,omitempty"`
+	Weight      []float32           `json:"weight,omitempty"`
+	Datasources []uint32            `json:"datasources,omitempty"`
+	Nodes       []uint64            `json:"nodes,omitempty"`
+	Metadata    *AnnotationMetadata `json:"metadata,omitempty"`
+}
+
+// annotationFields decodes fields of the annotation with encoding/json
+type annotationFields Annotation
+
+// UnmarshalJSON decodes the annotation, arrays of numbers are decoded without reflection
+func (a *Annotation) UnmarshalJSON(data []byte) error {
+	return unmarshalObject(data, func(key []byte, s *jsonScanner) (bool, error) {
+		var err error
+		switch string(key) {
+		case "duration":
+			a.Duration, err = s.float32s(0)
+		case "distance":
+			a.Distance, err = s.float32s(0)
+		case "speed":
+			a.Speed, err = s.float32s(0)
+		case "weight":
+			a.Weight, err = s.float32s(0)
+		case "datasources":
+			a.Datasources, err = s.uint32s()
+		case "nodes":
+			a.Nodes, err = s.uint64s()
+		default:
+			return false, nil
+		}
+		return true, err
+	}, (*annotationFields)(a))
+}
+
+// AnnotationMetadata contains names of the datasources referred by Annotation.Datasources
+type AnnotationMetadata struct {
+	DatasourceNames []string `json:"datasource_names"`
+}
+
+// DatasourceName returns a name of the datasource used for the i-th segment,
+// e.g. "lua profile" for the base profile speeds or a name of the traffic update file.
+// It returns an empty string if datasources or their metadata were not requested.
+func (a Annotation) DatasourceName(i int) string {
+	if i < 0 || i >= len(a.Datasources) || a.Metadata == nil {
+		return ""
+	}
+	if ds := int(a.Datasources[i]); ds < len(a.Metadata.DatasourceNames) {
+		return a.Metadata.DatasourceNames[ds]
+	}
+	return ""
 }
 
 // RouteStep represents a route geometry
 type RouteStep struct {
-	Distance      float32        `json:"distance"`
-	Duration      float32        `json:"duration"`
-	Geometry      Geometry       `json:"geometry"`
-	Name          string         `json:"name"`
-	Mode          string         `json:"mode"`
-	DrivingSide   string         `json:"driving_side"`
-	Weight        float32        `json:"weight"`
-	Maneuver      StepManeuver   `json:"maneuver"`
-	Intersections []Intersection `json:"intersections,omitempty"`
+	Distance            float32        `json:"distance"`
+	Duration            float32        `json:"duration"`
+	Geometry            Geometry       `json:"geometry"`
+	Name                string         `json:"name"`
+	Ref                 string         `json:"ref,omitempty"`
+	Pronunciation       string         `json:"pronunciation,omitempty"`
+	Destinations        string         `json:"destinations,omitempty"`
+	Exits               string         `json:"exits,omitempty"`
+	Mode                string         `json:"mode"`
+	DrivingSide         string         `json:"driving_side"`
+	Weight              float32        `json:"weight"`
+	Maneuver            StepManeuver   `json:"maneuver"`
+	Intersections       []Intersection `json:"intersections,omitempty"`
+	RotaryName          string         `json:"rotary_name,omitempty"`
+	RotaryPronunciation string         `json:"rotary_pronunciation,omitempty"`
 }
 
 type Intersection struct {
 	Location geo.Point `json:"location"`
 	Bearings []uint16  `json:"bearings"`
+	Classes  []string  `json:"classes,omitempty"`
 	Entry    []bool    `json:"entry"`
 	In       *uint32   `json:"in,omitempty"`
 	Out      *uint32   `json:"out,omitempty"`
@@ -84,23 +228,24 @@ type Intersection struct {
 }
 
 type Lane struct {
-	Indications []string `json:"indications"`
-	Valid       bool     `json:"valid"`
+	Indications []LaneIndication `json:"indications"`
+	Valid       bool             `json:"valid"`
 }
 
 // StepManeuver contains information about maneuver in step
 type StepManeuver struct {
-	Location      geo.Point `json:"location"`
-	BearingBefore float32   `json:"bearing_before"`
-	BearingAfter  float32   `json:"bearing_after"`
-	Type          string    `json:"type"`
-	Modifier      string    `json:"modifier,omitempty"`
-	Exit          *uint32   `json:"exit,omitempty"`
+	Location      geo.Point        `json:"location"`
+	BearingBefore float32          `json:"bearing_before"`
+	BearingAfter  float32          `json:"bearing_after"`
+	Type          ManeuverType     `json:"type"`
+	Modifier      ManeuverModifier `json:"modifier,omitempty"`
+	Exit          *uint32          `json:"exit,omitempty"`
 }
 
 func (r RouteRequest) request() *request {
 	opts := stepsOptions(r.Steps, r.Annotations, r.Overview, r.Geometries).
-		setStringer("continue_straight", r.ContinueStraight)
+		setStringer("continue_straight", r.ContinueStraight).
+		setStringer("alternatives", r.Alternatives)
 
 	if len(r.Waypoints) > 0 {
 		waypoints := ""
@@ -113,15 +258,11 @@ func (r RouteRequest) request() *request {
 		opts.set("waypoints", waypoints)
 	}
 
-	if len(r.Bearings) > 0 {
-		opts.set("bearings", bearings(r.Bearings))
-	}
-
 	return &request{
 		profile: r.Profile,
 		coords:  r.Coordinates,
 		service: "route",
-		options: opts,
+		options: r.GeneralOptions.withBearings(r.Bearings).apply(opts),
 	}
 }
 
@@ -1,9 +1,16 @@
 package osrm
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
 	"testing"
 
+	geo "github.com/paulmach/go.geo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmptyRouteRequestOptions(t *testing.T) {
@@ -28,6 +35,23 @@ func TestRouteRequestOptionsWithBearings(t *testing.T) {
 		req.request().options.encode())
 }
 
+func TestRouteRequestGeneralOptions(t *testing.T) {
+	req := RouteRequest{
+		GeneralOptions: GeneralOptions{
+			Bearings: []Bearing{
+				{60, 380},
+				{45, 180},
+			},
+			Radiuses: NewRadiuses(10, 20),
+		},
+		ContinueStraight: ContinueStraightTrue,
+	}
+	assert.Equal(
+		t,
+		"bearings=60%2C380%3B45%2C180&continue_straight=true&geometries=polyline6&radiuses=10;20",
+		req.request().options.encode())
+}
+
 func TestRouteRequestOverviewOption(t *testing.T) {
 	req := RouteRequest{
 		Overview:         OverviewFull,
@@ -51,3 +75,199 @@ func TestRouteRequestGeometryOption(t *testing.T) {
 		"annotations=false&continue_straight=true&geometries=polyline6&steps=false",
 		req.request().options.encode())
 }
+
+func TestRouteRequestWithSeveralAnnotations(t *testing.T) {
+	req := RouteRequest{
+		Annotations: NewAnnotations(AnnotationsDuration, AnnotationsDistance, AnnotationsSpeed),
+	}
+	assert.Equal(
+		t,
+		"annotations=duration%2Cdistance%2Cspeed&geometries=polyline6",
+		req.request().options.encode())
+}
+
+func TestUnmarshalAnnotation(t *testing.T) {
+	in := []byte(`{
+		"metadata": {"datasource_names": ["lua profile", "traffic"]},
+		"datasources": [0, 1, 1],
+		"weight": [1.5, 2.3, 0.4],
+		"nodes": [49772551, 49772552, 49786799, 49786800],
+		"distance": [12.3, 20.7, 4.6],
+		"duration": [1.5, 2.3, 0.4],
+		"speed": [8.2, 9, 11.5]
+	}`)
+
+	var a Annotation
+	require.NoError(t, json.Unmarshal(in, &a))
+
+	assert.Equal(t, []float32{1.5, 2.3, 0.4}, a.Duration)
+	assert.Equal(t, []float32{12.3, 20.7, 4.6}, a.Distance)
+	assert.Equal(t, []float32{8.2, 9, 11.5}, a.Speed)
+	assert.Equal(t, []float32{1.5, 2.3, 0.4}, a.Weight)
+	assert.Equal(t, []uint32{0, 1, 1}, a.Datasources)
+	assert.Equal(t, []uint64{49772551, 49772552, 49786799, 49786800}, a.Nodes)
+	require.NotNil(t, a.Metadata)
+	assert.Equal(t, []string{"lua profile", "traffic"}, a.Metadata.DatasourceNames)
+
+	assert.Equal(t, "lua profile", a.DatasourceName(0))
+	assert.Equal(t, "traffic", a.DatasourceName(2))
+	assert.Equal(t, "", a.DatasourceName(3))
+	assert.Equal(t, "", Annotation{Datasources: []uint32{0}}.DatasourceName(0))
+}
+
+func TestRouteRequestAlternativesOption(t *testing.T) {
+	req := RouteRequest{
+		Alternatives: AlternativesTrue,
+	}
+	assert.Equal(
+		t,
+		"alternatives=true&geometries=polyline6",
+		req.request().options.encode())
+
+	req = RouteRequest{
+		Alternatives: AlternativesNumber(3),
+	}
+	assert.Equal(
+		t,
+		"alternatives=3&geometries=polyline6",
+		req.request().options.encode())
+}
+
+func TestRouteResponseAlternatives(t *testing.T) {
+	primary := Route{
+		Distance: 1200,
+		Duration: 120,
+		Geometry: NewGeometryFromPointSet(geo.PointSet{
+			{-73.9900, 40.7150}, {-73.9900, 40.7200}, {-73.9850, 40.7200},
+		}),
+	}
+	// follows the primary route for the most part
+	similar := Route{
+		Distance: 1100,
+		Duration: 130,
+		Geometry: NewGeometryFromPointSet(geo.PointSet{
+			{-73.9900, 40.7150}, {-73.9900, 40.7195}, {-73.9895, 40.7200}, {-73.9850, 40.7200},
+		}),
+	}
+	// goes around another block
+	different := Route{
+		Distance: 1300,
+		Duration: 110,
+		Geometry: NewGeometryFromPointSet(geo.PointSet{
+			{-73.9900, 40.7150}, {-73.9850, 40.7150}, {-73.9850, 40.7200},
+		}),
+	}
+
+	resp := RouteResponse{Routes: []Route{primary, similar, different}}
+
+	require.NotNil(t, resp.Fastest())
+	assert.Equal(t, different.Duration, resp.Fastest().Duration)
+	require.NotNil(t, resp.Shortest())
+	assert.Equal(t, similar.Distance, resp.Shortest().Distance)
+	require.NotNil(t, resp.MostDifferent())
+	assert.Equal(t, different.Duration, resp.MostDifferent().Duration)
+}
+
+func TestRouteResponseWithoutAlternatives(t *testing.T) {
+	resp := RouteResponse{}
+	assert.Nil(t, resp.Fastest())
+	assert.Nil(t, resp.Shortest())
+	assert.Nil(t, resp.MostDifferent())
+
+	resp = RouteResponse{Routes: []Route{{Duration: 1}}}
+	assert.Equal(t, &resp.Routes[0], resp.Fastest())
+	assert.Nil(t, resp.MostDifferent())
+}
+
+func TestDivergence(t *testing.T) {
+	g := NewGeometryFromPointSet(geo.PointSet{{-73.99, 40.715}, {-73.99, 40.72}})
+	assert.Equal(t, 0.0, divergence(g, g))
+	assert.Equal(t, 1.0, divergence(g, NewGeometryFromPointSet(geo.PointSet{{-73.98, 40.715}})))
+	assert.Equal(t, 1.0, divergence(g, Geometry{}))
+	assert.Equal(t, 0.0, divergence(Geometry{}, g))
+}
+
+func TestAnnotationUnmarshalJSON(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/route_response_full.json")
+	require.NoError(t, err)
+
+	var r RouteResponse
+	require.NoError(t, json.Unmarshal(data, &r))
+	require.NotEmpty(t, r.Routes[0].Legs)
+	leg := r.Routes[0].Legs[0]
+	assert.Equal(t, []float32{1.5, 3.5, 2.3, 3, 4.5, 2.9, 0.4, 0.3, 0.3, 1.3, 8.8, 9.5, 17.4, 2.3}, leg.Annotation.Duration)
+	assert.Equal(t, float32(20.708867), leg.Annotation.Distance[1])
+
+	var a Annotation
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"duration": [1, null, 2.5],
+		"speed": [10.2],
+		"weight": null,
+		"datasources": [0, 1],
+		"nodes": [1234567890123, 42],
+		"metadata": {"datasource_names": ["lua profile", "traffic"]}
+	}`), &a))
+	assert.Equal(t, Annotation{
+		Duration:    []float32{1, 0, 2.5},
+		Speed:       []float32{10.2},
+		Datasources: []uint32{0, 1},
+		Nodes:       []uint64{1234567890123, 42},
+		Metadata:    &AnnotationMetadata{DatasourceNames: []string{"lua profile", "traffic"}},
+	}, a)
+	assert.Equal(t, "traffic", a.DatasourceName(1))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"nodes": [-1]}`), &a))
+}
+
+// annotationJSON generates an annotation of the given number of segments
+func annotationJSON(size int) []byte {
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	array := func(name string, value func() string) {
+		buf.WriteString(`"` + name + `": [`)
+		for i := 0; i < size; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(value())
+		}
+		buf.WriteString(`],`)
+	}
+	float := func() string { return strconv.FormatFloat(r.Float64()*100, 'f', 1, 64) }
+
+	buf.WriteByte('{')
+	array("duration", float)
+	array("distance", float)
+	array("speed", float)
+	array("weight", float)
+	array("datasources", func() string { return strconv.Itoa(r.Intn(2)) })
+	array("nodes", func() string { return strconv.FormatUint(r.Uint64()>>16, 10) })
+	buf.WriteString(`"metadata": {"datasource_names": ["lua profile", "traffic"]}}`)
+	return buf.Bytes()
+}
+
+func BenchmarkAnnotationUnmarshalJSON(b *testing.B) {
+	data := annotationJSON(100000)
+
+	b.Run("scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var a Annotation
+			if err := a.UnmarshalJSON(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var a annotationFields
+			if err := json.Unmarshal(data, &a); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
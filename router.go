@@ -0,0 +1,28 @@
+package osrm
+
+// Router maps profiles to OSRM servers, as OSRM serves every profile by a separate instance
+type Router struct {
+	// Profiles maps profile names to their servers
+	Profiles map[string]Upstream
+	// Default serves profiles missing in the map. Requests of such profiles fail
+	// with UnknownProfileError if it is not set.
+	Default *Upstream
+}
+
+// Upstream represents either a single OSRM server or a pool of servers
+type Upstream struct {
+	// ServerURL is an OSRM server URL, it is ignored if a pool is set
+	ServerURL string
+	Pool      *Pool
+}
+
+// route returns an upstream of the profile
+func (r *Router) route(profile string) (Upstream, error) {
+	if u, ok := r.Profiles[profile]; ok {
+		return u, nil
+	}
+	if r.Default != nil {
+		return *r.Default, nil
+	}
+	return Upstream{}, &UnknownProfileError{Profile: profile}
+}
@@ -0,0 +1,61 @@
+package osrm
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterRoutesProfiles(t *testing.T) {
+	var car, bike, other int32
+	carServer, bikeServer := nearestServer(&car, http.StatusOK), nearestServer(&bike, http.StatusOK)
+	otherServer := nearestServer(&other, http.StatusOK)
+	defer carServer.Close()
+	defer bikeServer.Close()
+	defer otherServer.Close()
+
+	bikePool := NewPool(PoolConfig{ServerURLs: []string{bikeServer.URL}, HealthCheckInterval: -1})
+	defer bikePool.Close()
+
+	osrm := NewWithConfig(Config{Router: &Router{
+		Profiles: map[string]Upstream{
+			"car":  {ServerURL: carServer.URL},
+			"bike": {Pool: bikePool},
+		},
+		Default: &Upstream{ServerURL: otherServer.URL},
+	}})
+
+	for _, profile := range []string{"car", "bike", "bike", "foot"} {
+		_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: profile, Coordinates: geometry})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&car))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&bike))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&other))
+}
+
+func TestRouterFailsOnUnknownProfile(t *testing.T) {
+	var calls int32
+	ts := nearestServer(&calls, http.StatusOK)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{
+		ServerURL: ts.URL,
+		Router:    &Router{Profiles: map[string]Upstream{"car": {ServerURL: ts.URL}}},
+	})
+
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "bike", Coordinates: geometry})
+	require.EqualError(t, err, `osrm5: unknown profile "bike"`)
+	require.IsType(t, &UnknownProfileError{}, err)
+	assert.Equal(t, "bike", err.(*UnknownProfileError).Profile)
+
+	_, err = osrm.Nearest(context.Background(), NearestRequest{Coordinates: geometry})
+	require.Equal(t, ErrEmptyProfileName, err)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
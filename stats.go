@@ -0,0 +1,132 @@
+package osrm
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// CallStats represents a latency breakdown of the last http request of a call
+type CallStats struct {
+	// DNS is a time of the host lookup, zero for reused connections
+	DNS time.Duration
+	// Connect is a time to establish a connection, zero for reused connections
+	Connect time.Duration
+	// TLS is a time of the TLS handshake
+	TLS time.Duration
+	// Wait is a time from the request written to the first response byte, mostly OSRM computation
+	Wait time.Duration
+	// Transfer is a time from the first response byte to the whole body read.
+	// It includes decoding of JSON responses without their own decoders, since encoding/json
+	// decodes them before the read is complete.
+	Transfer time.Duration
+	// Decode is a time of decoding of responses read as a whole: binary responses and tables
+	Decode time.Duration
+	// Total is a total time of the http request
+	Total time.Duration
+	// ReusedConn tells whether the connection was reused
+	ReusedConn bool
+	// BodySize is a size of the response body in bytes
+	BodySize int
+	// Attempts is a number of http requests made by the call
+	Attempts int
+}
+
+type callStatsKey struct{}
+
+// WithCallStats returns a context collecting stats of a call made with it into the given value.
+// The stats are set once the call returns.
+func WithCallStats(ctx context.Context, stats *CallStats) context.Context {
+	return context.WithValue(ctx, callStatsKey{}, stats)
+}
+
+// tracer times phases of an http request, its hooks could be called from other goroutines
+type tracer struct {
+	mu sync.Mutex
+
+	start, dnsStart, dnsDone, connectStart, connectDone time.Time
+	tlsStart, tlsDone, wrote, firstByte, read, end      time.Time
+
+	reused bool
+	size   int
+}
+
+func newTracer() *tracer {
+	return &tracer{start: time.Now()}
+}
+
+// context returns a context tracing the request with the tracer
+func (t *tracer) context(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { t.now(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { t.now(&t.dnsDone) },
+		ConnectStart:      func(string, string) { t.now(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { t.now(&t.connectDone) },
+		TLSHandshakeStart: func() { t.now(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.now(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.now(&t.wrote) },
+		GotFirstResponseByte: func() { t.now(&t.firstByte) },
+	})
+}
+
+func (t *tracer) now(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+// bodyRead marks the response body read, nil tracer does nothing
+func (t *tracer) bodyRead(size int) {
+	if t == nil {
+		return
+	}
+	t.now(&t.read)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.size = size
+}
+
+// stats finishes the request and returns its stats
+func (t *tracer) stats() CallStats {
+	t.now(&t.end)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sent := t.wrote
+	if sent.IsZero() {
+		sent = t.start
+	}
+	transferred := t.read
+	if transferred.IsZero() {
+		transferred = t.end
+	}
+	return CallStats{
+		DNS:        between(t.dnsStart, t.dnsDone),
+		Connect:    between(t.connectStart, t.connectDone),
+		TLS:        between(t.tlsStart, t.tlsDone),
+		Wait:       between(sent, t.firstByte),
+		Transfer:   between(t.firstByte, transferred),
+		Decode:     between(t.read, t.end),
+		Total:      between(t.start, t.end),
+		ReusedConn: t.reused,
+		BodySize:   t.size,
+	}
+}
+
+// between returns a duration between two moments, zero if any of them is unknown
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,85 @@
+package osrm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallStats(t *testing.T) {
+	const body = `{"code": "Ok", "waypoints": []}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	osrm := NewFromURL(ts.URL)
+
+	var stats CallStats
+	_, err := osrm.Nearest(WithCallStats(context.Background(), &stats), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.Attempts)
+	assert.Equal(t, len(body), stats.BodySize)
+	assert.False(t, stats.ReusedConn)
+	assert.True(t, stats.Connect > 0)
+	assert.True(t, stats.Wait >= 20*time.Millisecond, stats.Wait)
+	assert.True(t, stats.Transfer >= 20*time.Millisecond, stats.Transfer)
+	assert.True(t, stats.Total >= stats.Wait+stats.Transfer+stats.Decode)
+
+	_, err = osrm.Nearest(WithCallStats(context.Background(), &stats), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+
+	assert.True(t, stats.ReusedConn)
+	assert.Equal(t, time.Duration(0), stats.Connect)
+	assert.Equal(t, time.Duration(0), stats.DNS)
+}
+
+func TestCallStatsWithRetries(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code": "Ok", "waypoints": []}`))
+	}))
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, RetryPolicy: testRetryPolicy})
+
+	var stats CallStats
+	_, err := osrm.Nearest(WithCallStats(context.Background(), &stats), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Attempts)
+}
+
+func TestCallStatsInInterceptors(t *testing.T) {
+	var calls int32
+	ts := nearestServer(&calls, http.StatusOK)
+	defer ts.Close()
+
+	var stats CallStats
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, invoke Invoker) error {
+			err := invoke(ctx, call)
+			stats = call.Stats
+			return err
+		},
+	}})
+
+	_, err := osrm.Nearest(context.Background(), NearestRequest{Profile: "car", Coordinates: geometry})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Attempts)
+	assert.True(t, stats.BodySize > 0)
+}
@@ -0,0 +1,60 @@
+package osrm
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Strategy picks a server of a pool for a request.
+// Pick is called concurrently with a list of available servers, it returns nil if the list is empty.
+type Strategy interface {
+	Pick(backends []*Backend) *Backend
+}
+
+// RoundRobinStrategy picks servers in turn
+type RoundRobinStrategy struct {
+	next uint64
+}
+
+// Pick implements Strategy
+func (s *RoundRobinStrategy) Pick(backends []*Backend) *Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	n := atomic.AddUint64(&s.next, 1) - 1
+	return backends[n%uint64(len(backends))]
+}
+
+// LeastOutstandingStrategy picks a server with the least number of requests in progress
+type LeastOutstandingStrategy struct{}
+
+// Pick implements Strategy
+func (LeastOutstandingStrategy) Pick(backends []*Backend) *Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	best := backends[0]
+	for _, b := range backends[1:] {
+		if b.Outstanding() < best.Outstanding() {
+			best = b
+		}
+	}
+	return best
+}
+
+// EWMALatencyStrategy picks a server with the least moving average of latency weighted
+// by a number of requests in progress. Servers without requests yet are picked first.
+type EWMALatencyStrategy struct{}
+
+// Pick implements Strategy
+func (EWMALatencyStrategy) Pick(backends []*Backend) *Backend {
+	var best *Backend
+	bestCost := math.Inf(1)
+	for _, b := range backends {
+		cost := float64(b.Latency()) * float64(b.Outstanding()+1)
+		if cost < bestCost {
+			best, bestCost = b, cost
+		}
+	}
+	return best
+}
@@ -0,0 +1,51 @@
+package osrm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundRobinStrategy(t *testing.T) {
+	backends := []*Backend{{url: "a"}, {url: "b"}, {url: "c"}}
+	s := &RoundRobinStrategy{}
+
+	var urls []string
+	for i := 0; i < 4; i++ {
+		urls = append(urls, s.Pick(backends).URL())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, urls)
+}
+
+func TestLeastOutstandingStrategy(t *testing.T) {
+	backends := []*Backend{{url: "a", outstanding: 2}, {url: "b", outstanding: 1}, {url: "c", outstanding: 1}}
+	assert.Equal(t, "b", LeastOutstandingStrategy{}.Pick(backends).URL())
+}
+
+func TestEWMALatencyStrategy(t *testing.T) {
+	a, b := &Backend{url: "a"}, &Backend{url: "b"}
+	a.observe(10 * time.Millisecond)
+	b.observe(30 * time.Millisecond)
+	assert.Equal(t, "a", EWMALatencyStrategy{}.Pick([]*Backend{a, b}).URL())
+
+	a.outstanding = 3
+	assert.Equal(t, "b", EWMALatencyStrategy{}.Pick([]*Backend{a, b}).URL())
+
+	c := &Backend{url: "c"}
+	assert.Equal(t, "c", EWMALatencyStrategy{}.Pick([]*Backend{a, b, c}).URL())
+}
+
+func TestStrategiesWithoutBackends(t *testing.T) {
+	for _, s := range []Strategy{&RoundRobinStrategy{}, LeastOutstandingStrategy{}, EWMALatencyStrategy{}} {
+		assert.Nil(t, s.Pick(nil))
+	}
+}
+
+func TestBackendLatency(t *testing.T) {
+	b := &Backend{}
+	b.observe(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, b.Latency())
+	b.observe(200 * time.Millisecond)
+	assert.Equal(t, 130*time.Millisecond, b.Latency())
+}
@@ -2,15 +2,29 @@ package osrm
 
 // TableRequest represents a request to the table method
 type TableRequest struct {
+	GeneralOptions
 	Profile               string
 	Coordinates           Geometry
 	Sources, Destinations []int
+	Annotations           TableAnnotations
+	// FallbackSpeed is a speed in meters per second used to compute crow flies
+	// durations and distances for pairs without a route. Fallback is disabled if not set.
+	FallbackSpeed      float64
+	FallbackCoordinate FallbackCoordinate
+	// ScaleFactor scales the durations of the table, OSRM default value of 1 is used if not set.
+	ScaleFactor float64
 }
 
 // TableResponse resresents a response from the table method
 type TableResponse struct {
 	ResponseStatus
-	Durations [][]float32 `json:"durations"`
+	// Durations and Distances are empty unless requested with annotations
+	Durations    Matrix     `json:"durations"`
+	Distances    Matrix     `json:"distances"`
+	Sources      []Waypoint `json:"sources"`
+	Destinations []Waypoint `json:"destinations"`
+	// FallbackSpeedCells lists [row, column] pairs of the cells estimated with the fallback speed
+	FallbackSpeedCells [][2]int `json:"fallback_speed_cells"`
 }
 
 func (r TableRequest) request() *request {
@@ -21,11 +35,35 @@ func (r TableRequest) request() *request {
 	if len(r.Destinations) > 0 {
 		opts.addInt("destinations", r.Destinations...)
 	}
+	opts.setStringer("annotations", r.Annotations)
+	if r.FallbackSpeed > 0 {
+		opts.addFloat("fallback_speed", r.FallbackSpeed)
+	}
+	opts.setStringer("fallback_coordinate", r.FallbackCoordinate)
+	if r.ScaleFactor > 0 {
+		opts.addFloat("scale_factor", r.ScaleFactor)
+	}
 
 	return &request{
 		profile: r.Profile,
 		coords:  r.Coordinates,
 		service: "table",
-		options: opts,
+		options: r.GeneralOptions.apply(opts),
 	}
 }
+
+// tableResponseFields decodes fields of the table response with encoding/json
+type tableResponseFields TableResponse
+
+// UnmarshalJSON decodes the response, durations and distances are decoded without reflection
+func (r *TableResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalObject(data, func(key []byte, s *jsonScanner) (bool, error) {
+		switch string(key) {
+		case "durations":
+			return true, r.Durations.decode(s)
+		case "distances":
+			return true, r.Distances.decode(s)
+		}
+		return false, nil
+	}, (*tableResponseFields)(r))
+}
@@ -1,9 +1,15 @@
 package osrm
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmptyTableRequestOptions(t *testing.T) {
@@ -18,3 +24,86 @@ func TestNotEmptyTableRequestOptions(t *testing.T) {
 	}
 	assert.Equal(t, "destinations=1;3&sources=0;1;2", req.request().options.encode())
 }
+
+func TestTableRequestOptionsWithFallback(t *testing.T) {
+	req := TableRequest{
+		Annotations:        TableAnnotationsDistance,
+		FallbackSpeed:      13.9,
+		FallbackCoordinate: FallbackCoordinateInput,
+		ScaleFactor:        0.5,
+	}
+	assert.Equal(t, "annotations=distance&fallback_coordinate=input&fallback_speed=13.9&scale_factor=0.5", req.request().options.encode())
+}
+
+func TestTableResponseUnmarshalJSON(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/table_response_annotations.json")
+	require.NoError(t, err)
+
+	var r TableResponse
+	require.NoError(t, json.Unmarshal(data, &r))
+
+	var expected tableResponseFields
+	require.NoError(t, json.Unmarshal(data, &expected))
+	assert.Equal(t, TableResponse(expected), r)
+	assert.Equal(t, [][]float32{{0, 39, 46.8}, {39.5, 0, 34.2}}, r.Durations.Float32s(-1))
+	assert.NotEmpty(t, r.Destinations)
+
+	assert.EqualError(t, json.Unmarshal([]byte(`{"code": "Ok", "durations": [[1, 2], [3]]}`), &r), "matrix row 1 has 1 columns, 2 expected")
+}
+
+// tableJSON generates a table response with the given number of sources and destinations
+func tableJSON(size int) []byte {
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	buf.WriteString(`{"code": "Ok", "durations": [`)
+	for i := 0; i < size; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for j := 0; j < size; j++ {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			if r.Intn(100) == 0 {
+				buf.WriteString("null")
+			} else {
+				buf.WriteString(strconv.FormatFloat(r.Float64()*10000, 'f', 1, 64))
+			}
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString(`], "sources": [], "destinations": []}`)
+	return buf.Bytes()
+}
+
+func BenchmarkTableResponseUnmarshalJSON(b *testing.B) {
+	data := tableJSON(1000)
+
+	b.Run("scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var r TableResponse
+			if err := r.UnmarshalJSON(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var r struct {
+				ResponseStatus
+				Durations    [][]*float32 `json:"durations"`
+				Sources      []Waypoint   `json:"sources"`
+				Destinations []Waypoint   `json:"destinations"`
+			}
+			if err := json.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package osrm
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	geo "github.com/paulmach/go.geo"
+)
+
+// tableCell is a cached table cell with the waypoints of its source and destination
+type tableCell struct {
+	// duration and distance are NaN for unreachable pairs or values which were not requested
+	duration, distance  float32
+	fallback            bool
+	source, destination *Waypoint
+	dataVersion         string
+	stored              time.Time
+}
+
+// tableCache keeps table cells by pairs of coordinates and requests only the missing part of tables
+type tableCache struct {
+	versionedCache
+}
+
+func newTableCache(c Cache, maxAge time.Duration) *tableCache {
+	return &tableCache{versionedCache{cache: c, maxAge: maxAge}}
+}
+
+// tableKeys are cache keys of the request coordinates sharing the prefix with the rest of options
+type tableKeys struct {
+	prefix string
+	points []string
+}
+
+func (k tableKeys) cell(source, destination int) string {
+	return k.prefix + "/" + k.points[source] + "/" + k.points[destination]
+}
+
+// table looks up cells of the request in the cache, fetches the sub-table of sources
+// and destinations with missing cells and assembles the response. Invalid requests are
+// sent as they are for OSRM to reject them.
+func (c *tableCache) table(ctx context.Context, r TableRequest, fetch func(context.Context, TableRequest) (*TableResponse, error)) (*TableResponse, error) {
+	n := r.Coordinates.Length()
+	sources, destinations := tableIndices(r.Sources, n), tableIndices(r.Destinations, n)
+	if len(sources) == 0 || len(destinations) == 0 || !validTableRequest(r, n) {
+		return fetch(ctx, r)
+	}
+
+	keys := newTableKeys(r)
+	version := c.currentDataVersion()
+	cells := make([]*tableCell, len(sources)*len(destinations))
+	missingRows, missingCols := make([]bool, len(sources)), make([]bool, len(destinations))
+	cached := 0
+	for i, s := range sources {
+		for j, d := range destinations {
+			if v, ok := c.cache.Get(keys.cell(s, d)); ok {
+				if cell, ok := v.(*tableCell); ok && cell.dataVersion == version && !c.expired(cell.stored) {
+					cells[i*len(destinations)+j] = cell
+					cached++
+					continue
+				}
+			}
+			missingRows[i], missingCols[j] = true, true
+		}
+	}
+
+	if cached == 0 {
+		return c.fetchAll(ctx, r, keys, fetch)
+	}
+	if cached < len(cells) {
+		rows, cols := selected(missingRows), selected(missingCols)
+		resp, err := fetch(ctx, subTableRequest(r, pick(sources, rows), pick(destinations, cols)))
+		if err != nil {
+			return nil, err
+		}
+		if resp.DataVersion != version {
+			// the server has other data, the whole table is requested again not to mix cells of both versions
+			c.updateDataVersion(resp.DataVersion)
+			return c.fetchAll(ctx, r, keys, fetch)
+		}
+
+		fetched := c.store(keys, pick(sources, rows), pick(destinations, cols), resp)
+		for i, row := range rows {
+			for j, col := range cols {
+				cells[row*len(destinations)+col] = fetched[i*len(cols)+j]
+			}
+		}
+	}
+
+	return assembleTable(r, version, len(sources), len(destinations), cells), nil
+}
+
+// fetchAll requests the whole table and caches its cells
+func (c *tableCache) fetchAll(ctx context.Context, r TableRequest, keys tableKeys, fetch func(context.Context, TableRequest) (*TableResponse, error)) (*TableResponse, error) {
+	resp, err := fetch(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	n := r.Coordinates.Length()
+	if c.updateDataVersion(resp.DataVersion) {
+		c.store(keys, tableIndices(r.Sources, n), tableIndices(r.Destinations, n), resp)
+	}
+	return resp, nil
+}
+
+// store caches cells of the response between the source and destination coordinates
+// and returns them in row-major order
+func (c *tableCache) store(keys tableKeys, sources, destinations []int, resp *TableResponse) []*tableCell {
+	fallback := make(map[[2]int]bool, len(resp.FallbackSpeedCells))
+	for _, rc := range resp.FallbackSpeedCells {
+		fallback[rc] = true
+	}
+
+	now := time.Now()
+	cells := make([]*tableCell, len(sources)*len(destinations))
+	for i, s := range sources {
+		for j, d := range destinations {
+			cell := &tableCell{
+				duration:    matrixValue(resp.Durations, i, j),
+				distance:    matrixValue(resp.Distances, i, j),
+				fallback:    fallback[[2]int{i, j}],
+				dataVersion: resp.DataVersion,
+				stored:      now,
+			}
+			if i < len(resp.Sources) {
+				cell.source = &resp.Sources[i]
+			}
+			if j < len(resp.Destinations) {
+				cell.destination = &resp.Destinations[j]
+			}
+			c.cache.Set(keys.cell(s, d), cell)
+			cells[i*len(destinations)+j] = cell
+		}
+	}
+	return cells
+}
+
+// assembleTable builds a response of the request from its cells in row-major order
+func assembleTable(r TableRequest, version string, rows, cols int, cells []*tableCell) *TableResponse {
+	resp := &TableResponse{ResponseStatus: ResponseStatus{Code: errorCodeOK, DataVersion: version}}
+
+	annotations := r.Annotations.String()
+	if annotations == "" || strings.Contains(annotations, "duration") {
+		resp.Durations = newMatrix(rows, cols)
+	}
+	if strings.Contains(annotations, "distance") {
+		resp.Distances = newMatrix(rows, cols)
+	}
+	for i, cell := range cells {
+		if resp.Durations.values != nil {
+			resp.Durations.values[i] = cell.duration
+		}
+		if resp.Distances.values != nil {
+			resp.Distances.values[i] = cell.distance
+		}
+		if cell.fallback {
+			resp.FallbackSpeedCells = append(resp.FallbackSpeedCells, [2]int{i / cols, i % cols})
+		}
+	}
+	resp.Durations = resp.Durations.withIndices(r.Sources, r.Destinations)
+	resp.Distances = resp.Distances.withIndices(r.Sources, r.Destinations)
+
+	if r.SkipWaypoints != SkipWaypointsTrue {
+		resp.Sources = make([]Waypoint, rows)
+		for i := range resp.Sources {
+			if w := cells[i*cols].source; w != nil {
+				resp.Sources[i] = *w
+			}
+		}
+		resp.Destinations = make([]Waypoint, cols)
+		for j := range resp.Destinations {
+			if w := cells[j].destination; w != nil {
+				resp.Destinations[j] = *w
+			}
+		}
+	}
+	return resp
+}
+
+// newTableKeys returns cache keys of the request. Per-coordinate options are the part of
+// coordinate keys, other options except sources and destinations are the part of the prefix.
+func newTableKeys(r TableRequest) tableKeys {
+	base := r
+	base.Sources, base.Destinations = nil, nil
+	base.Bearings, base.Radiuses, base.Hints, base.Approaches = nil, nil, nil, nil
+
+	keys := tableKeys{
+		prefix: "table/" + r.Profile + "?" + base.request().options.encode(),
+		points: make([]string, r.Coordinates.Length()),
+	}
+	for i, p := range r.Coordinates.PointSet {
+		var b strings.Builder
+		b.WriteString(quantize(p.Lng()))
+		b.WriteByte(',')
+		b.WriteString(quantize(p.Lat()))
+		if i < len(r.Bearings) {
+			b.WriteString(";b=" + r.Bearings[i].String())
+		}
+		if i < len(r.Radiuses) {
+			b.WriteString(";r=" + r.Radiuses[i].String())
+		}
+		if i < len(r.Approaches) {
+			b.WriteString(";a=" + r.Approaches[i].String())
+		}
+		if i < len(r.Hints) {
+			b.WriteString(";h=" + r.Hints[i])
+		}
+		keys.points[i] = b.String()
+	}
+	return keys
+}
+
+// subTableRequest returns the request limited to the given source and destination coordinates
+func subTableRequest(r TableRequest, sources, destinations []int) TableRequest {
+	var coordinates []int
+	positions := map[int]int{}
+	position := func(c int) int {
+		if p, ok := positions[c]; ok {
+			return p
+		}
+		positions[c] = len(coordinates)
+		coordinates = append(coordinates, c)
+		return positions[c]
+	}
+
+	sub := r
+	sub.Sources, sub.Destinations = make([]int, len(sources)), make([]int, len(destinations))
+	for i, s := range sources {
+		sub.Sources[i] = position(s)
+	}
+	for i, d := range destinations {
+		sub.Destinations[i] = position(d)
+	}
+
+	points := make(geo.PointSet, len(coordinates))
+	for i, c := range coordinates {
+		points[i] = r.Coordinates.PointSet[c]
+	}
+	sub.Coordinates = NewGeometryFromPointSet(points)
+
+	sub.Bearings, sub.Radiuses, sub.Hints, sub.Approaches = nil, nil, nil, nil
+	for _, c := range coordinates {
+		if len(r.Bearings) > 0 {
+			sub.Bearings = append(sub.Bearings, r.Bearings[c])
+		}
+		if len(r.Radiuses) > 0 {
+			sub.Radiuses = append(sub.Radiuses, r.Radiuses[c])
+		}
+		if len(r.Hints) > 0 {
+			sub.Hints = append(sub.Hints, r.Hints[c])
+		}
+		if len(r.Approaches) > 0 {
+			sub.Approaches = append(sub.Approaches, r.Approaches[c])
+		}
+	}
+	return sub
+}
+
+// validTableRequest checks that sources and destinations refer to the coordinates
+// and per-coordinate options are set for every coordinate if they are set
+func validTableRequest(r TableRequest, n int) bool {
+	for _, indices := range [][]int{r.Sources, r.Destinations} {
+		for _, i := range indices {
+			if i < 0 || i >= n {
+				return false
+			}
+		}
+	}
+	for _, l := range []int{len(r.Bearings), len(r.Radiuses), len(r.Hints), len(r.Approaches)} {
+		if l != 0 && l != n {
+			return false
+		}
+	}
+	return true
+}
+
+// tableIndices returns the coordinate indices of table sources or destinations, all coordinates if not set
+func tableIndices(indices []int, n int) []int {
+	if len(indices) > 0 {
+		return indices
+	}
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// selected returns positions of true values
+func selected(flags []bool) []int {
+	var positions []int
+	for i, ok := range flags {
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// pick returns values at the given positions
+func pick(values, positions []int) []int {
+	picked := make([]int, len(positions))
+	for i, p := range positions {
+		picked[i] = values[p]
+	}
+	return picked
+}
+
+// matrixValue returns a raw matrix value, NaN for unreachable pairs and missing matrices
+func matrixValue(m Matrix, row, col int) float32 {
+	if row >= m.rows || col >= m.cols {
+		return float32(math.NaN())
+	}
+	return m.values[row*m.cols+col]
+}
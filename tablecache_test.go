@@ -0,0 +1,279 @@
+package osrm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	geo "github.com/paulmach/go.geo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tableServer serves tables with durations of lng(source)*10+lng(destination) and ten times larger distances,
+// the pair from lng 2 to lng 3 is unreachable. It records numbers of cells requested by every call.
+type tableServer struct {
+	*httptest.Server
+	version atomic.Value
+
+	mu    sync.Mutex
+	cells []int
+}
+
+func newTableServer(t *testing.T) *tableServer {
+	s := &tableServer{}
+	s.version.Store("v1")
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		points := requestPoints(r)
+		query, err := parseRawQuery(r.URL.RawQuery)
+		require.NoError(t, err)
+		sources, destinations := tableIndices(query["sources"], len(points)), tableIndices(query["destinations"], len(points))
+
+		s.mu.Lock()
+		s.cells = append(s.cells, len(sources)*len(destinations))
+		s.mu.Unlock()
+
+		resp := map[string]interface{}{"code": "Ok", "data_version": s.version.Load()}
+		durations, distances := make([][]*float32, len(sources)), make([][]*float32, len(sources))
+		for i, src := range sources {
+			durations[i], distances[i] = make([]*float32, len(destinations)), make([]*float32, len(destinations))
+			for j, dst := range destinations {
+				if points[src].Lng() == 2 && points[dst].Lng() == 3 {
+					continue
+				}
+				v := float32(points[src].Lng()*10 + points[dst].Lng())
+				d := v * 10
+				durations[i][j], distances[i][j] = &v, &d
+			}
+		}
+		resp["durations"], resp["distances"] = durations, distances
+		resp["sources"], resp["destinations"] = tableWaypoints(points, sources), tableWaypoints(points, destinations)
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	return s
+}
+
+func (s *tableServer) requestedCells() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.cells...)
+}
+
+// requestPoints decodes coordinates of the request path
+func requestPoints(r *http.Request) geo.PointSet {
+	encoded := strings.TrimSuffix(r.URL.Path[strings.Index(r.URL.Path, "polyline(")+len("polyline("):], ")")
+	return geo.Decode(encoded).PointSet
+}
+
+func parseRawQuery(raw string) (map[string][]int, error) {
+	query := map[string][]int{}
+	for _, param := range strings.Split(raw, "&") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 || (kv[0] != "sources" && kv[0] != "destinations") {
+			continue
+		}
+		for _, v := range strings.Split(kv[1], ";") {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			query[kv[0]] = append(query[kv[0]], n)
+		}
+	}
+	return query, nil
+}
+
+func tableWaypoints(points geo.PointSet, indices []int) []Waypoint {
+	waypoints := make([]Waypoint, len(indices))
+	for i, c := range indices {
+		waypoints[i] = Waypoint{Name: strconv.FormatFloat(points[c].Lng(), 'f', -1, 64), Location: points[c]}
+	}
+	return waypoints
+}
+
+func tableCoordinates(lngs ...float64) Geometry {
+	points := make(geo.PointSet, len(lngs))
+	for i, lng := range lngs {
+		points[i] = geo.Point{lng, 0}
+	}
+	return NewGeometryFromPointSet(points)
+}
+
+func TestTableCacheRequestsMissingCells(t *testing.T) {
+	ts := newTableServer(t)
+	defer ts.Close()
+
+	cache := NewLRUCache(100, time.Minute)
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, TableCache: cache})
+	table := func(r TableRequest) *TableResponse {
+		r.Profile, r.Annotations = "car", TableAnnotationsDurationDistance
+		resp, err := osrm.Table(context.Background(), r)
+		require.NoError(t, err)
+		return resp
+	}
+
+	table(TableRequest{Coordinates: tableCoordinates(1, 2, 3), Sources: []int{0, 1}, Destinations: []int{2}})
+	assert.Equal(t, []int{2}, ts.requestedCells())
+	assert.Equal(t, 2, cache.Len())
+
+	// the new source and the new destination require a 3x2 sub-table
+	r := table(TableRequest{Coordinates: tableCoordinates(4, 1, 2, 3, 1), Sources: []int{0, 1, 2}, Destinations: []int{3, 4}})
+	assert.Equal(t, []int{2, 6}, ts.requestedCells())
+
+	assert.Equal(t, "v1", r.DataVersion)
+	assert.Equal(t, [][]float32{{43, 41}, {13, 11}, {-1, 21}}, r.Durations.Float32s(-1))
+	assert.Equal(t, [][]float32{{430, 410}, {130, 110}, {-1, 210}}, r.Distances.Float32s(-1))
+	v, ok := r.Durations.At(1, 3)
+	assert.True(t, ok)
+	assert.Equal(t, float32(13), v)
+	assert.False(t, r.Durations.Reachable(2, 3))
+	require.Len(t, r.Sources, 3)
+	require.Len(t, r.Destinations, 2)
+	assert.Equal(t, []string{"4", "1", "2"}, []string{r.Sources[0].Name, r.Sources[1].Name, r.Sources[2].Name})
+	assert.Equal(t, []string{"3", "1"}, []string{r.Destinations[0].Name, r.Destinations[1].Name})
+
+	// every cell is cached
+	cached := table(TableRequest{Coordinates: tableCoordinates(4, 1, 2, 3, 1), Sources: []int{0, 1, 2}, Destinations: []int{3, 4}})
+	assert.Equal(t, []int{2, 6}, ts.requestedCells())
+	assert.Equal(t, r.Durations.Float32s(-1), cached.Durations.Float32s(-1))
+	assert.Equal(t, r.Distances.Float32s(-1), cached.Distances.Float32s(-1))
+	assert.Equal(t, r.Sources, cached.Sources)
+	assert.Equal(t, r.Destinations, cached.Destinations)
+
+	// other options are cached separately
+	durations, err := osrm.Table(context.Background(), TableRequest{
+		Profile:      "car",
+		Coordinates:  tableCoordinates(1, 2, 3),
+		Sources:      []int{0, 1},
+		Destinations: []int{2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 6, 2}, ts.requestedCells())
+	assert.Equal(t, [][]float32{{13}, {-1}}, durations.Durations.Float32s(-1))
+}
+
+func TestTableCacheDataVersion(t *testing.T) {
+	ts := newTableServer(t)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, TableCache: NewLRUCache(100, time.Minute)})
+	table := func(destinations []int, lngs ...float64) *TableResponse {
+		resp, err := osrm.Table(context.Background(), TableRequest{
+			Profile:      "car",
+			Coordinates:  tableCoordinates(lngs...),
+			Destinations: destinations,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	table(nil, 1, 2)
+	assert.Equal(t, []int{4}, ts.requestedCells())
+
+	// a new data version outdates the cached cells, the whole table is requested again
+	ts.version.Store("v2")
+	r := table([]int{0, 1}, 1, 2, 3)
+	assert.Equal(t, []int{4, 2, 6}, ts.requestedCells())
+	assert.Equal(t, "v2", r.DataVersion)
+	assert.Equal(t, [][]float32{{11, 12}, {21, 22}, {31, 32}}, r.Durations.Float32s(-1))
+
+	table(nil, 2, 1)
+	assert.Equal(t, []int{4, 2, 6}, ts.requestedCells())
+}
+
+func TestTableCacheExpiration(t *testing.T) {
+	ts := newTableServer(t)
+	defer ts.Close()
+
+	osrm := NewWithConfig(Config{ServerURL: ts.URL, TableCache: NewLRUCache(100, 20*time.Millisecond)})
+	for i := 0; i < 2; i++ {
+		_, err := osrm.Table(context.Background(), TableRequest{Profile: "car", Coordinates: tableCoordinates(1, 2)})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{4}, ts.requestedCells())
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := osrm.Table(context.Background(), TableRequest{Profile: "car", Coordinates: tableCoordinates(1, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 4}, ts.requestedCells())
+}
+
+func TestTableCacheSendsInvalidRequests(t *testing.T) {
+	coordinates := tableCoordinates(1, 2, 3)
+	for name, r := range map[string]TableRequest{
+		"source out of range":      {Sources: []int{0, 3}},
+		"negative destination":     {Destinations: []int{-1}},
+		"short bearings":           {GeneralOptions: GeneralOptions{Bearings: []Bearing{{0, 90}}}},
+		"short radiuses":           {GeneralOptions: GeneralOptions{Radiuses: []Radius{10, 20}}},
+		"short hints":              {GeneralOptions: GeneralOptions{Hints: []string{"a"}}},
+		"short approaches":         {GeneralOptions: GeneralOptions{Approaches: []Approach{ApproachCurb}}},
+		"long per-coordinate list": {GeneralOptions: GeneralOptions{Radiuses: []Radius{10, 20, 30, 40}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newTableCache(NewLRUCache(100, time.Minute), time.Minute)
+			fetch := func(ctx context.Context, r TableRequest) (*TableResponse, error) {
+				return &TableResponse{
+					ResponseStatus: ResponseStatus{Code: errorCodeOK},
+					Durations:      newMatrix(len(tableIndices(r.Sources, 3)), len(tableIndices(r.Destinations, 3))),
+				}, nil
+			}
+			// cells of a valid request are cached, so the invalid one would need a sub-table
+			_, err := c.table(context.Background(), TableRequest{Profile: "car", Coordinates: coordinates, Sources: []int{0}}, fetch)
+			require.NoError(t, err)
+
+			r.Profile, r.Coordinates = "car", coordinates
+			invalid := errors.New("InvalidQuery")
+			var sent TableRequest
+			_, err = c.table(context.Background(), r, func(ctx context.Context, r TableRequest) (*TableResponse, error) {
+				sent = r
+				return nil, invalid
+			})
+			assert.Equal(t, invalid, err)
+			assert.Equal(t, r, sent)
+		})
+	}
+}
+
+func TestSubTableRequest(t *testing.T) {
+	r := TableRequest{
+		Profile:      "car",
+		Coordinates:  tableCoordinates(1, 2, 3, 4),
+		Sources:      []int{0, 1, 2},
+		Destinations: []int{1, 3},
+		GeneralOptions: GeneralOptions{
+			Radiuses: []Radius{10, 20, 30, 40},
+			Hints:    []string{"a", "b", "c", "d"},
+		},
+	}
+
+	sub := subTableRequest(r, []int{2}, []int{1, 3})
+	assert.Equal(t, tableCoordinates(3, 2, 4), sub.Coordinates)
+	assert.Equal(t, []int{0}, sub.Sources)
+	assert.Equal(t, []int{1, 2}, sub.Destinations)
+	assert.Equal(t, []Radius{30, 20, 40}, sub.Radiuses)
+	assert.Equal(t, []string{"c", "b", "d"}, sub.Hints)
+	assert.Nil(t, sub.Bearings)
+	assert.Equal(t, []int{0, 1, 2}, r.Sources)
+}
+
+func TestTableKeys(t *testing.T) {
+	r := TableRequest{
+		Profile:     "car",
+		Coordinates: tableCoordinates(1, 2.0000001),
+		Sources:     []int{0},
+		Annotations: TableAnnotationsDistance,
+		GeneralOptions: GeneralOptions{
+			Approaches: []Approach{ApproachCurb, ApproachDefault},
+		},
+	}
+	keys := newTableKeys(r)
+	assert.Equal(t, "table/car?annotations=distance/1,0;a=curb/2,0;a=", keys.cell(0, 1))
+}
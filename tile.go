@@ -0,0 +1,134 @@
+package osrm
+
+import "fmt"
+
+// Tile represents a decoded vector tile returned by the tile method.
+// OSRM puts road segments with their speeds into the "speeds" layer
+// and turn penalties into the "turns" layer.
+type Tile struct {
+	X, Y, Z int
+	Layers  []TileLayer
+}
+
+// TileLayer represents a named set of features of a vector tile
+type TileLayer struct {
+	Name     string
+	Version  uint32
+	Extent   uint32
+	Features []TileFeature
+}
+
+// TileFeature represents a single geometry of a vector tile layer with its properties.
+// Geometry consists of points in the tile extent coordinates: every point of a point feature,
+// every line of a line feature or every ring of a polygon feature is a separate part.
+type TileFeature struct {
+	ID         uint64
+	Type       TileGeometryType
+	Properties map[string]interface{}
+	Geometry   [][]TilePoint
+}
+
+// TilePoint represents a point in the tile extent coordinates
+type TilePoint struct {
+	X, Y int32
+}
+
+// TileGeometryType represents a geometry type of a vector tile feature
+type TileGeometryType uint32
+
+// Supported vector tile geometry types
+const (
+	TileGeometryUnknown    TileGeometryType = 0
+	TileGeometryPoint      TileGeometryType = 1
+	TileGeometryLineString TileGeometryType = 2
+	TileGeometryPolygon    TileGeometryType = 3
+)
+
+// Layer returns a layer by its name or nil if the tile has no such layer
+func (t *Tile) Layer(name string) *TileLayer {
+	for i := range t.Layers {
+		if t.Layers[i].Name == name {
+			return &t.Layers[i]
+		}
+	}
+	return nil
+}
+
+// Speeds returns the layer with road segments and their speeds
+func (t *Tile) Speeds() *TileLayer {
+	return t.Layer("speeds")
+}
+
+// Turns returns the layer with turns and their penalties
+func (t *Tile) Turns() *TileLayer {
+	return t.Layer("turns")
+}
+
+// UnmarshalBinary decodes a tile from Mapbox Vector Tile format
+func (t *Tile) UnmarshalBinary(data []byte) error {
+	layers, err := decodeTile(data)
+	if err != nil {
+		return err
+	}
+	t.Layers = layers
+	return nil
+}
+
+// Float returns a numeric property converted to float64
+func (f TileFeature) Float(key string) (float64, bool) {
+	switch v := f.Properties[key].(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// String returns a string property
+func (f TileFeature) String(key string) (string, bool) {
+	v, ok := f.Properties[key].(string)
+	return v, ok
+}
+
+// Bool returns a boolean property
+func (f TileFeature) Bool(key string) (bool, bool) {
+	v, ok := f.Properties[key].(bool)
+	return v, ok
+}
+
+// tileResponse wraps a tile to be decoded from a binary body on success
+// and from a JSON body on failure
+type tileResponse struct {
+	ResponseStatus
+	Tile
+}
+
+// UnmarshalBinary decodes a successful tile response
+func (r *tileResponse) UnmarshalBinary(data []byte) error {
+	r.Code = errorCodeOK
+	return r.Tile.UnmarshalBinary(data)
+}
+
+// tileIndex represents tile coordinates in XYZ tiling scheme
+type tileIndex struct {
+	x, y, z int
+}
+
+// String returns tile coordinates in OSRM format
+func (t tileIndex) String() string {
+	return fmt.Sprintf("tile(%d,%d,%d)", t.x, t.y, t.z)
+}
+
+func tileRequest(profile string, z, x, y int) *request {
+	return &request{
+		profile: profile,
+		tile:    &tileIndex{x: x, y: y, z: z},
+		service: "tile",
+		format:  "mvt",
+	}
+}
@@ -0,0 +1,193 @@
+package osrm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pbWriter encodes protocol buffers messages for tile fixtures
+type pbWriter []byte
+
+func (w pbWriter) varint(v uint64) pbWriter {
+	for v >= 0x80 {
+		w = append(w, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(w, byte(v))
+}
+
+func (w pbWriter) key(field, wire int) pbWriter {
+	return w.varint(uint64(field<<3 | wire))
+}
+
+func (w pbWriter) bytes(field int, b []byte) pbWriter {
+	return append(w.key(field, wireBytes).varint(uint64(len(b))), b...)
+}
+
+func (w pbWriter) uint(field int, v uint64) pbWriter {
+	return w.key(field, wireVarint).varint(v)
+}
+
+func (w pbWriter) double(field int, v float64) pbWriter {
+	w = w.key(field, wireFixed64)
+	bits := math.Float64bits(v)
+	for i := uint(0); i < 8; i++ {
+		w = append(w, byte(bits>>(8*i)))
+	}
+	return w
+}
+
+func (w pbWriter) packed(field int, v ...uint32) pbWriter {
+	var p pbWriter
+	for _, n := range v {
+		p = p.varint(uint64(n))
+	}
+	return w.bytes(field, p)
+}
+
+func zigzag(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+// tileFixture builds a tile similar to the one returned by OSRM
+func tileFixture() []byte {
+	speed := pbWriter{}.
+		uint(1, 1).
+		packed(2, 0, 0, 1, 1, 2, 2).
+		uint(3, uint64(TileGeometryLineString)).
+		packed(4, 9, zigzag(10), zigzag(20), 18, zigzag(5), zigzag(-5), zigzag(5), zigzag(0))
+	// the turn feature is tagged with "turn_type=turn" only
+	turn := pbWriter{}.
+		packed(2, 3, 3).
+		uint(3, uint64(TileGeometryPoint)).
+		packed(4, 9, zigzag(20), zigzag(15))
+
+	speeds := pbWriter{}.
+		uint(15, 2).
+		bytes(1, []byte("speeds")).
+		bytes(2, speed).
+		bytes(3, []byte("speed")).
+		bytes(3, []byte("is_small")).
+		bytes(3, []byte("datasource")).
+		bytes(4, pbWriter{}.uint(5, 42)).
+		bytes(4, pbWriter{}.uint(7, 1)).
+		bytes(4, pbWriter{}.bytes(1, []byte("lua profile"))).
+		uint(5, 4096)
+	turns := pbWriter{}.
+		uint(15, 2).
+		bytes(1, []byte("turns")).
+		bytes(2, turn).
+		bytes(3, []byte("turn_angle")).
+		bytes(3, []byte("cost")).
+		bytes(4, pbWriter{}.key(6, wireVarint).varint(uint64(zigzag(-90)))).
+		bytes(4, pbWriter{}.double(3, 1.5)).
+		bytes(3, []byte("weight")).
+		bytes(3, []byte("turn_type")).
+		bytes(4, pbWriter{}.double(3, 2)).
+		bytes(4, pbWriter{}.bytes(1, []byte("turn")))
+
+	return pbWriter{}.bytes(3, speeds).bytes(3, turns)
+}
+
+func TestTileUnmarshalBinary(t *testing.T) {
+	var tile Tile
+	require.NoError(t, tile.UnmarshalBinary(tileFixture()))
+	require.Len(t, tile.Layers, 2)
+
+	speeds := tile.Speeds()
+	require.NotNil(t, speeds)
+	assert.Equal(t, uint32(2), speeds.Version)
+	assert.Equal(t, uint32(4096), speeds.Extent)
+	require.Len(t, speeds.Features, 1)
+
+	segment := speeds.Features[0]
+	assert.Equal(t, uint64(1), segment.ID)
+	assert.Equal(t, TileGeometryLineString, segment.Type)
+	assert.Equal(t, [][]TilePoint{{{10, 20}, {15, 15}, {20, 15}}}, segment.Geometry)
+	speed, ok := segment.Float("speed")
+	assert.True(t, ok)
+	assert.Equal(t, float64(42), speed)
+	isSmall, ok := segment.Bool("is_small")
+	assert.True(t, ok)
+	assert.True(t, isSmall)
+	datasource, ok := segment.String("datasource")
+	assert.True(t, ok)
+	assert.Equal(t, "lua profile", datasource)
+
+	turns := tile.Turns()
+	require.NotNil(t, turns)
+	require.Len(t, turns.Features, 1)
+	turn := turns.Features[0]
+	assert.Equal(t, TileGeometryPoint, turn.Type)
+	assert.Equal(t, [][]TilePoint{{{20, 15}}}, turn.Geometry)
+	assert.Equal(t, map[string]interface{}{"turn_type": "turn"}, turn.Properties)
+
+	assert.Nil(t, tile.Layer("unknown"))
+}
+
+func TestTileUnmarshalBinaryValues(t *testing.T) {
+	values := []struct {
+		name     string
+		value    pbWriter
+		expected interface{}
+	}{
+		{"string", pbWriter{}.bytes(1, []byte("foo")), "foo"},
+		{"double", pbWriter{}.double(3, 0.5), 0.5},
+		{"int", pbWriter{}.uint(4, 7), int64(7)},
+		{"uint", pbWriter{}.uint(5, 7), uint64(7)},
+		{"sint", pbWriter{}.uint(6, uint64(zigzag(-7))), int64(-7)},
+		{"bool", pbWriter{}.uint(7, 0), false},
+	}
+	for _, v := range values {
+		t.Run(v.name, func(t *testing.T) {
+			layer := pbWriter{}.
+				bytes(1, []byte("layer")).
+				bytes(2, pbWriter{}.packed(2, 0, 0)).
+				bytes(3, []byte("key")).
+				bytes(4, v.value)
+
+			var tile Tile
+			require.NoError(t, tile.UnmarshalBinary(pbWriter{}.bytes(3, layer)))
+			assert.Equal(t, v.expected, tile.Layers[0].Features[0].Properties["key"])
+		})
+	}
+}
+
+func TestTileUnmarshalBinaryPolygon(t *testing.T) {
+	feature := pbWriter{}.
+		uint(3, uint64(TileGeometryPolygon)).
+		packed(4, 9, zigzag(0), zigzag(0), 18, zigzag(10), zigzag(0), zigzag(0), zigzag(10), 15)
+	layer := pbWriter{}.bytes(1, []byte("layer")).bytes(2, feature)
+
+	var tile Tile
+	require.NoError(t, tile.UnmarshalBinary(pbWriter{}.bytes(3, layer)))
+	assert.Equal(t, [][]TilePoint{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}}, tile.Layers[0].Features[0].Geometry)
+}
+
+func TestTileUnmarshalBinaryMalformed(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated layer", pbWriter{}.bytes(3, []byte("layer"))[:4]},
+		{"tag out of range", pbWriter{}.bytes(3, pbWriter{}.bytes(2, pbWriter{}.packed(2, 0, 0)))},
+		{"line to without move to", pbWriter{}.bytes(3, pbWriter{}.bytes(2, pbWriter{}.packed(4, 10, 0, 0)))},
+		{"missing parameters", pbWriter{}.bytes(3, pbWriter{}.bytes(2, pbWriter{}.packed(4, 9, 0)))},
+		{"unknown command", pbWriter{}.bytes(3, pbWriter{}.bytes(2, pbWriter{}.packed(4, 11)))},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var tile Tile
+			assert.Error(t, tile.UnmarshalBinary(c.data))
+		})
+	}
+}
+
+func TestTileRequestURL(t *testing.T) {
+	url, err := tileRequest("car", 14, 4823, 6160).URL("localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost/tile/v1/car/tile(4823,6160,14).mvt", url)
+}
@@ -0,0 +1,43 @@
+package osrm
+
+// TripRequest represents a request to the trip method
+type TripRequest struct {
+	GeneralOptions
+	Profile     string
+	Coordinates Geometry
+	Roundtrip   Roundtrip
+	Source      Source
+	Destination Destination
+	Steps       Steps
+	Annotations Annotations
+	Overview    Overview
+	Geometries  Geometries
+}
+
+// TripResponse represents a response from the trip method
+type TripResponse struct {
+	ResponseStatus
+	Trips     []Route        `json:"trips"`
+	Waypoints []TripWaypoint `json:"waypoints"`
+}
+
+// TripWaypoint represents an input coordinate snapped to the road network and its place in the trips
+type TripWaypoint struct {
+	Waypoint
+	TripsIndex    int `json:"trips_index"`
+	WaypointIndex int `json:"waypoint_index"`
+}
+
+func (r TripRequest) request() *request {
+	opts := stepsOptions(r.Steps, r.Annotations, r.Overview, r.Geometries).
+		setStringer("roundtrip", r.Roundtrip).
+		setStringer("source", r.Source).
+		setStringer("destination", r.Destination)
+
+	return &request{
+		profile: r.Profile,
+		coords:  r.Coordinates,
+		service: "trip",
+		options: r.GeneralOptions.apply(opts),
+	}
+}
@@ -0,0 +1,34 @@
+package osrm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmptyTripRequestOptions(t *testing.T) {
+	req := TripRequest{}
+	assert.Equal(
+		t,
+		"geometries=polyline6",
+		req.request().options.encode())
+}
+
+func TestTripRequestOptions(t *testing.T) {
+	req := TripRequest{
+		Roundtrip:   RoundtripFalse,
+		Source:      SourceFirst,
+		Destination: DestinationLast,
+		Steps:       StepsTrue,
+		Overview:    OverviewFalse,
+		GeneralOptions: GeneralOptions{
+			Bearings: []Bearing{
+				{60, 380},
+			},
+		},
+	}
+	assert.Equal(
+		t,
+		"bearings=60%2C380&destination=last&geometries=polyline6&overview=false&roundtrip=false&source=first&steps=true",
+		req.request().options.encode())
+}
@@ -3,7 +3,9 @@ package osrm
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	geo "github.com/paulmach/go.geo"
@@ -97,11 +99,50 @@ const (
 	AnnotationsSpeed       Annotations = "speed"
 )
 
+// NewAnnotations combines several annotations to be requested at once,
+// e.g. NewAnnotations(AnnotationsDuration, AnnotationsDistance, AnnotationsSpeed).
+func NewAnnotations(values ...Annotations) Annotations {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = v.String()
+	}
+	return Annotations(strings.Join(s, ","))
+}
+
 // String returns Annotations as a string
 func (a Annotations) String() string {
 	return string(a)
 }
 
+// TableAnnotations represents a annotations param for osrm5 table request
+type TableAnnotations string
+
+// Supported table annotations param values
+const (
+	TableAnnotationsDuration         TableAnnotations = "duration"
+	TableAnnotationsDistance         TableAnnotations = "distance"
+	TableAnnotationsDurationDistance TableAnnotations = "duration,distance"
+)
+
+// String returns TableAnnotations as a string
+func (a TableAnnotations) String() string {
+	return string(a)
+}
+
+// FallbackCoordinate represents a fallback_coordinate param for osrm5 table request
+type FallbackCoordinate string
+
+// Supported fallback_coordinate param values
+const (
+	FallbackCoordinateInput   FallbackCoordinate = "input"
+	FallbackCoordinateSnapped FallbackCoordinate = "snapped"
+)
+
+// String returns FallbackCoordinate as a string
+func (f FallbackCoordinate) String() string {
+	return string(f)
+}
+
 // Steps represents a steps param for osrm5 request
 type Steps string
 
@@ -174,12 +215,92 @@ func (c ContinueStraight) String() string {
 	return string(c)
 }
 
+// Alternatives represents alternatives OSRM routing parameter
+type Alternatives string
+
+// Alternatives values
+const (
+	AlternativesTrue  Alternatives = "true"
+	AlternativesFalse Alternatives = "false"
+)
+
+// AlternativesNumber requests to search for at most n alternative routes
+func AlternativesNumber(n int) Alternatives {
+	return Alternatives(strconv.Itoa(n))
+}
+
+// String returns Alternatives as string
+func (a Alternatives) String() string {
+	return string(a)
+}
+
+// Roundtrip represents roundtrip OSRM trip parameter
+type Roundtrip string
+
+// Roundtrip values
+const (
+	RoundtripTrue  Roundtrip = "true"
+	RoundtripFalse Roundtrip = "false"
+)
+
+// String returns Roundtrip as string
+func (r Roundtrip) String() string {
+	return string(r)
+}
+
+// Source represents source OSRM trip parameter
+type Source string
+
+// Source values
+const (
+	SourceAny   Source = "any"
+	SourceFirst Source = "first"
+)
+
+// String returns Source as string
+func (s Source) String() string {
+	return string(s)
+}
+
+// Destination represents destination OSRM trip parameter
+type Destination string
+
+// Destination values
+const (
+	DestinationAny  Destination = "any"
+	DestinationLast Destination = "last"
+)
+
+// String returns Destination as string
+func (d Destination) String() string {
+	return string(d)
+}
+
+// Format represents an encoding of OSRM responses
+type Format string
+
+// Supported response formats
+const (
+	FormatJSON        Format = "json"
+	FormatFlatbuffers Format = formatFlatbuffers
+)
+
+// String returns Format as a string
+func (f Format) String() string {
+	return string(f)
+}
+
 // request contains parameters for OSRM query
 type request struct {
 	profile string
 	coords  Geometry
+	tile    *tileIndex
 	service string
 	options options
+	format  string
+	header  http.Header
+	// observe is called with every http request made
+	observe func(url string, statusCode int, stats CallStats)
 }
 
 // URL generates a url for OSRM request
@@ -190,23 +311,121 @@ func (r *request) URL(serverURL string) (string, error) {
 	if r.profile == "" {
 		return "", ErrEmptyProfileName
 	}
-	if r.coords.Length() == 0 {
+	if r.tile == nil && r.coords.Length() == 0 {
 		return "", ErrNoCoordinates
 	}
 	// http://{server}/{service}/{version}/{profile}/{coordinates}[.{format}]?option=value&option=value
 	url := strings.Join([]string{
-		serverURL, // server
-		r.service, // service
-		version,   // version
-		r.profile, // profile
-		"polyline(" + url.PathEscape(r.coords.Polyline(polyline5Factor)) + ")", // coordinates
+		serverURL,       // server
+		r.service,       // service
+		version,         // version
+		r.profile,       // profile
+		r.coordinates(), // coordinates
 	}, "/")
+	if r.format != "" {
+		url += "." + r.format // format
+	}
 	if len(r.options) > 0 {
 		url += "?" + r.options.encode() // options
 	}
 	return url, nil
 }
 
+// coordinates returns coordinates of the request encoded for URL path
+func (r *request) coordinates() string {
+	if r.tile != nil {
+		return r.tile.String()
+	}
+	return "polyline(" + url.PathEscape(r.coords.Polyline(polyline5Factor)) + ")"
+}
+
+// Radius limits the search of the road segment to given radius in meters
+type Radius float64
+
+const (
+	// RadiusDefault keeps OSRM default search radius for the coordinate
+	RadiusDefault Radius = 0
+	// RadiusUnlimited removes the limit of the search radius
+	RadiusUnlimited Radius = -1
+)
+
+// NewRadiuses converts radiuses in meters, e.g. former MatchRequest.Radiuses, to Radius values
+func NewRadiuses(meters ...float64) []Radius {
+	radiuses := make([]Radius, len(meters))
+	for i, m := range meters {
+		radiuses[i] = Radius(m)
+	}
+	return radiuses
+}
+
+// String returns Radius as a string, it is empty for the default radius
+func (r Radius) String() string {
+	if r == RadiusDefault {
+		return ""
+	}
+	if r < 0 {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(float64(r), 'f', -1, 64)
+}
+
+// Approach represents a side of the road to approach the coordinate from
+type Approach string
+
+// Supported approaches param values
+const (
+	ApproachDefault      Approach = ""
+	ApproachCurb         Approach = "curb"
+	ApproachUnrestricted Approach = "unrestricted"
+)
+
+// String returns Approach as a string
+func (a Approach) String() string {
+	return string(a)
+}
+
+// Snapping represents a snapping param for osrm5 request
+type Snapping string
+
+// Supported snapping param values
+const (
+	SnappingDefault Snapping = "default"
+	SnappingAny     Snapping = "any"
+)
+
+// String returns Snapping as a string
+func (s Snapping) String() string {
+	return string(s)
+}
+
+// GenerateHints represents a generate_hints param for osrm5 request
+type GenerateHints string
+
+// Supported generate_hints param values
+const (
+	GenerateHintsTrue  GenerateHints = "true"
+	GenerateHintsFalse GenerateHints = "false"
+)
+
+// String returns GenerateHints as a string
+func (g GenerateHints) String() string {
+	return string(g)
+}
+
+// SkipWaypoints represents a skip_waypoints param for osrm5 request
+type SkipWaypoints string
+
+// Supported skip_waypoints param values
+const (
+	SkipWaypointsTrue  SkipWaypoints = "true"
+	SkipWaypointsFalse SkipWaypoints = "false"
+)
+
+// String returns SkipWaypoints as a string
+func (s SkipWaypoints) String() string {
+	return string(s)
+}
+
 // Bearing limits the search to segments with given bearing in degrees towards true north in clockwise direction.
 type Bearing struct {
 	Value, Range uint16